@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+// changedZones returns the unique names of the zones of cfg whose desired
+// configuration differs from the one that dnsconfig.js produced at the git
+// revision ref. A zone is changed if its records, settings, registrar or
+// DNS providers (including the providers' settings) differ, or if it did
+// not exist at ref.
+//
+// cfg must not be normalized yet, so that both sides are compared in the
+// same state.
+func changedZones(args GetDNSConfigArgs, cfg *models.DNSConfig, ref string) (map[string]bool, error) {
+	if args.JSONFile != "" {
+		return nil, errors.New("--changed-since can not be used with --ir")
+	}
+
+	dir, err := os.MkdirTemp("", "dnscontrol-changed-since-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	jsFile, err := extractGitRevision(ref, args.JSFile, dir)
+	if err != nil {
+		return nil, fmt.Errorf("--changed-since %s: %w", ref, err)
+	}
+	oldArgs := args
+	oldArgs.JSFile = jsFile
+	old, err := GetDNSConfig(oldArgs)
+	if err != nil {
+		return nil, fmt.Errorf("--changed-since %s: %w", ref, err)
+	}
+
+	oldZones, err := zoneFingerprints(old)
+	if err != nil {
+		return nil, err
+	}
+	newZones, err := zoneFingerprints(cfg)
+	if err != nil {
+		return nil, err
+	}
+	changed := map[string]bool{}
+	for name, fp := range newZones {
+		if oldFp, ok := oldZones[name]; !ok || !bytes.Equal(fp, oldFp) {
+			changed[name] = true
+		}
+	}
+	return changed, nil
+}
+
+// zoneFingerprints returns the JSON of each zone of cfg, along with the
+// registrar and DNS providers it uses, keyed by unique name.
+func zoneFingerprints(cfg *models.DNSConfig) (map[string][]byte, error) {
+	fps := map[string][]byte{}
+	for _, dc := range cfg.Domains {
+		fp := struct {
+			Zone      *models.DomainConfig
+			Registrar *models.RegistrarConfig
+			Providers []*models.DNSProviderConfig
+		}{Zone: dc, Registrar: cfg.RegistrarsByName[dc.RegistrarName]}
+		for _, p := range dc.DNSProviderInstances { // Sorted by name.
+			fp.Providers = append(fp.Providers, cfg.DNSProvidersByName[p.Name])
+		}
+		b, err := json.Marshal(fp)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dc.Name, err)
+		}
+		// The unique name is not set until cfg is normalized. An empty tag
+		// is the same as no tag.
+		fps[strings.TrimSuffix(dc.Name, "!")] = b
+	}
+	return fps, nil
+}
+
+// extractGitRevision extracts the git repository that contains jsFile, as
+// of revision ref, into dir. It returns the path of jsFile in dir.
+func extractGitRevision(ref, jsFile, dir string) (string, error) {
+	abs, err := filepath.Abs(jsFile)
+	if err != nil {
+		return "", err
+	}
+	top, err := gitOutput(filepath.Dir(abs), "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	top = strings.TrimSpace(top)
+	// The top level is reported without symlinks.
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is not in the git repository %s", jsFile, top)
+	}
+
+	archive, err := gitOutput(top, "archive", "--format=tar", ref)
+	if err != nil {
+		return "", err
+	}
+	if err := extractTar(strings.NewReader(archive), dir); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// gitOutput runs git in dir and returns its output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// extractTar extracts the directories and regular files of a tar archive
+// into dir.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(name, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %q", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(name, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func Test_changedZones(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const head = `var REG = NewRegistrar("none", "NONE");
+var DSP = NewDnsProvider("bind", "BIND");
+require("./zones.js");
+`
+	git("init", "-q")
+	write("dnsconfig.js", head)
+	write("zones.js", `
+D("a.com", REG, DnsProvider(DSP), A("@", "1.2.3.4"));
+D("b.com", REG, DnsProvider(DSP), TXT("@", "v1"));
+D("c.com", REG, DnsProvider(DSP), A("@", "1.2.3.4"));
+`)
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+
+	// Change b.com in a required file, and add d.com.
+	write("zones.js", `
+D("a.com", REG, DnsProvider(DSP), A("@", "1.2.3.4"));
+D("b.com", REG, DnsProvider(DSP), TXT("@", "v2"));
+D("c.com", REG, DnsProvider(DSP), A("@", "1.2.3.4"));
+D("d.com", REG, DnsProvider(DSP));
+`)
+
+	args := GetDNSConfigArgs{ExecuteDSLArgs: ExecuteDSLArgs{JSFile: filepath.Join(dir, "dnsconfig.js")}}
+	cfg, err := GetDNSConfig(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := changedZones(args, cfg, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 || !changed["b.com"] || !changed["d.com"] {
+		t.Errorf("changed since HEAD: got %v", changed)
+	}
+
+	// A change to a provider's settings changes all of its zones.
+	git("commit", "-q", "-a", "-m", "second")
+	write("dnsconfig.js", `var REG = NewRegistrar("none", "NONE");
+var DSP = NewDnsProvider("bind", "BIND", {"default_soa": {"master": "ns1.example.com."}});
+require("./zones.js");
+`)
+	if cfg, err = GetDNSConfig(args); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err = changedZones(args, cfg, "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 4 {
+		t.Errorf("provider change: got %v", changed)
+	}
+
+	if _, err := changedZones(args, cfg, "no-such-ref"); err == nil {
+		t.Error("no-such-ref: expected an error")
+	}
+}
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+// ChangesItem is a machine-readable description of the work preview/push
+// found for one zone at one provider or registrar.
+type ChangesItem struct {
+	Domain    string `json:"domain"`
+	Provider  string `json:"provider,omitempty"`
+	Registrar string `json:"registrar,omitempty"`
+
+	// Action is one of "populate" (create the zone), "records" (update the
+	// zone's records), "nameservers" (update the delegation at the
+	// registrar) or "depopulate" (delete the zone).
+	Action string `json:"action"`
+
+	Changes     []ChangesRecord     `json:"changes,omitempty"`     // Record-level changes ("records" only).
+	Nameservers []string            `json:"nameservers,omitempty"` // Desired nameservers ("nameservers" only).
+	Corrections []ChangesCorrection `json:"corrections,omitempty"` // The corrections as generated by the provider.
+}
+
+// ChangesRecord is a diff2.Change in a form suitable for JSON.
+type ChangesRecord struct {
+	Verb        string         `json:"verb"` // CREATE, CHANGE, DELETE, REPORT
+	Key         ChangesKey     `json:"key"`
+	Old         models.Records `json:"old,omitempty"`
+	New         models.Records `json:"new,omitempty"`
+	HintOnlyTTL bool           `json:"hint_only_ttl,omitempty"`
+	Msgs        []string       `json:"msgs,omitempty"`
+}
+
+// ChangesKey is a models.RecordKey in a form suitable for JSON.
+type ChangesKey struct {
+	NameFQDN string `json:"name"`
+	Type     string `json:"type,omitempty"`
+}
+
+// ChangesCorrection is a correction's message. Action is false for
+// informational messages.
+type ChangesCorrection struct {
+	Msg    string `json:"msg"`
+	Action bool   `json:"action"`
+}
+
+// Valid values for --changes-format.
+var changesFormats = []string{"json", "ndjson"}
+
+// zoneResults stores the zonerecs.Result of each zone/provider pair, and
+// the delegation corrections of each zone, gathered during PHASE 2.  It is
+// safe for concurrent use.
+type zoneResults struct {
+	sync.Mutex
+	m           map[string]*zonerecs.Result
+	delegations map[string][]*models.Correction
+}
+
+func zoneResultsKey(zone *models.DomainConfig, providerName string) string {
+	return zone.GetUniqueName() + "\000" + providerName
+}
+
+func (zr *zoneResults) store(zone *models.DomainConfig, providerName string, r *zonerecs.Result) {
+	zr.Lock()
+	defer zr.Unlock()
+	if zr.m == nil {
+		zr.m = map[string]*zonerecs.Result{}
+	}
+	zr.m[zoneResultsKey(zone, providerName)] = r
+}
+
+func (zr *zoneResults) get(zone *models.DomainConfig, providerName string) *zonerecs.Result {
+	zr.Lock()
+	defer zr.Unlock()
+	return zr.m[zoneResultsKey(zone, providerName)]
+}
+
+func (zr *zoneResults) storeDelegation(zone *models.DomainConfig, corrections []*models.Correction) {
+	zr.Lock()
+	defer zr.Unlock()
+	if zr.delegations == nil {
+		zr.delegations = map[string][]*models.Correction{}
+	}
+	zr.delegations[zone.GetUniqueName()] = corrections
+}
+
+// delegation returns the registrar corrections of zone, and false if they
+// could not be gathered.
+func (zr *zoneResults) delegation(zone *models.DomainConfig) ([]*models.Correction, bool) {
+	zr.Lock()
+	defer zr.Unlock()
+	c, ok := zr.delegations[zone.GetUniqueName()]
+	return c, ok
+}
+
+// ansiColorRegex matches ansi color codes.
+var ansiColorRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripColors(s string) string {
+	return ansiColorRegex.ReplaceAllString(s, "")
+}
+
+func genChangesCorrections(corrections []*models.Correction) []ChangesCorrection {
+	var r []ChangesCorrection
+	for _, c := range corrections {
+		r = append(r, ChangesCorrection{Msg: stripColors(c.Msg), Action: c.F != nil})
+	}
+	return r
+}
+
+func genChangesRecords(changes diff2.ChangeList) []ChangesRecord {
+	var r []ChangesRecord
+	for _, c := range changes {
+		var msgs []string
+		for _, m := range c.Msgs {
+			msgs = append(msgs, stripColors(m))
+		}
+		r = append(r, ChangesRecord{
+			Verb:        c.Type.String(),
+			Key:         ChangesKey{NameFQDN: c.Key.NameFQDN, Type: c.Key.Type},
+			Old:         c.Old,
+			New:         c.New,
+			HintOnlyTTL: c.HintOnlyTTL,
+			Msgs:        msgs,
+		})
+	}
+	return r
+}
+
+// genChangesItem generates the ChangesItem for a zone's populate,
+// depopulate, or registrar corrections.
+func genChangesItem(action string, zname string, corrections []*models.Correction, pname string) *ChangesItem {
+	return &ChangesItem{
+		Domain:      zname,
+		Provider:    pname,
+		Action:      action,
+		Corrections: genChangesCorrections(corrections),
+	}
+}
+
+// genRecordsChangesItem generates the ChangesItem for the record changes of
+// a zone at a DNS provider.
+func genRecordsChangesItem(zone *models.DomainConfig, corrections []*models.Correction, pname string, zr *zoneResults) *ChangesItem {
+	item := genChangesItem("records", zone.GetUniqueName(), corrections, pname)
+	if r := zr.get(zone, pname); r != nil {
+		item.Changes = genChangesRecords(r.RecordChanges())
+	}
+	return item
+}
+
+// genNameserversChangesItem generates the ChangesItem for the delegation
+// of a zone at its registrar.
+func genNameserversChangesItem(zone *models.DomainConfig, corrections []*models.Correction) *ChangesItem {
+	item := genChangesItem("nameservers", zone.GetUniqueName(), corrections, "")
+	item.Registrar = zone.RegistrarName
+	for _, ns := range zone.Nameservers {
+		item.Nameservers = append(item.Nameservers, ns.Name)
+	}
+	return item
+}
+
+// writeChanges writes the changes document to fname. If fname is empty,
+// nothing is written.
+func writeChanges(fname string, format string, items []*ChangesItem) error {
+	if fname == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "", "json":
+		if items == nil {
+			items = []*ChangesItem{}
+		}
+		b, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	case "ndjson":
+		enc := json.NewEncoder(f)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown changes format %q", format)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+)
+
+func Test_writeChanges(t *testing.T) {
+	rcOld := &models.RecordConfig{Type: "A", TTL: 300}
+	rcOld.SetLabel("www", "example.com")
+	rcOld.SetTarget("1.2.3.4")
+	rcNew := &models.RecordConfig{Type: "A", TTL: 600}
+	rcNew.SetLabel("www", "example.com")
+	rcNew.SetTarget("1.2.3.4")
+
+	changes := diff2.ChangeList{{
+		Type:        diff2.CHANGE,
+		Key:         rcOld.Key(),
+		Old:         models.Records{rcOld},
+		New:         models.Records{rcNew},
+		Msgs:        []string{"\x1b[33m± MODIFY-TTL www.example.com A 1.2.3.4 ttl=(300->600)\x1b[0m"},
+		HintOnlyTTL: true,
+	}}
+	item := genChangesItem("records", "example.com", []*models.Correction{{Msg: "opaque", F: func() error { return nil }}}, "bind")
+	item.Changes = genChangesRecords(changes)
+	items := []*ChangesItem{item, genChangesItem("populate", "example.net", nil, "bind")}
+
+	fname := filepath.Join(t.TempDir(), "changes.ndjson")
+	if err := writeChanges(fname, "ndjson", items); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), b)
+	}
+
+	var got ChangesItem
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(got.Changes))
+	}
+	c := got.Changes[0]
+	if c.Verb != "CHANGE" || !c.HintOnlyTTL || c.Key.NameFQDN != "www.example.com" || c.Key.Type != "A" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if c.Old[0].TTL != 300 || c.New[0].TTL != 600 || c.New[0].GetTargetField() != "1.2.3.4" {
+		t.Errorf("unexpected records: rcOld=%v rcNew=%v", c.Old[0], c.New[0])
+	}
+	if c.Msgs[0] != "± MODIFY-TTL www.example.com A 1.2.3.4 ttl=(300->600)" {
+		t.Errorf("colors not stripped: %q", c.Msgs[0])
+	}
+	if len(got.Corrections) != 1 || got.Corrections[0].Msg != "opaque" || !got.Corrections[0].Action {
+		t.Errorf("unexpected corrections: %+v", got.Corrections)
+	}
+}
+
+func Test_genRecordsChangesItem_splitHorizon(t *testing.T) {
+	outside := &models.DomainConfig{Name: "example.com"}
+	inside := &models.DomainConfig{Name: "example.com!inside"}
+	outside.UpdateSplitHorizonNames()
+	inside.UpdateSplitHorizonNames()
+
+	zr := &zoneResults{}
+	for _, zone := range []*models.DomainConfig{outside, inside} {
+		if got := genRecordsChangesItem(zone, nil, "bind", zr).Domain; got != zone.GetUniqueName() {
+			t.Errorf("records item for %s: got domain %q", zone.GetUniqueName(), got)
+		}
+		if got := genNameserversChangesItem(zone, nil).Domain; got != zone.GetUniqueName() {
+			t.Errorf("nameservers item for %s: got domain %q", zone.GetUniqueName(), got)
+		}
+	}
+}
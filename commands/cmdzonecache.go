@@ -1,6 +1,11 @@
 package commands
 
-import "github.com/StackExchange/dnscontrol/v4/providers"
+import (
+	"context"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/providers"
+)
 
 // FYI(tlim): This file was originally called zonecache.go. To remove any
 // confusion between it and pkg/zonecache, we've renamed it. We've also added
@@ -11,7 +16,7 @@ func NewCmdZoneCache() *cmdZoneCache {
 	return &cmdZoneCache{}
 }
 
-func (zc *cmdZoneCache) zoneList(name string, lister providers.ZoneLister) (*[]string, error) {
+func (zc *cmdZoneCache) zoneList(ctx context.Context, name string, lister providers.ZoneLister) (*[]string, error) {
 	zc.Lock()
 	defer zc.Unlock()
 
@@ -23,7 +28,7 @@ func (zc *cmdZoneCache) zoneList(name string, lister providers.ZoneLister) (*[]s
 		return v, nil
 	}
 
-	zones, err := lister.ListZones()
+	zones, err := models.CallContext(ctx, lister.ListZones)
 	if err != nil {
 		return nil, err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/pkg/js"
 	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
 	"github.com/StackExchange/dnscontrol/v4/pkg/version"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zoneglob"
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 )
@@ -285,6 +286,7 @@ func (args *GetCredentialsArgs) flags() []cli.Flag {
 type FilterArgs struct {
 	Providers string
 	Domains   string
+	Select    string
 }
 
 func (args *FilterArgs) flags() []cli.Flag {
@@ -292,50 +294,49 @@ func (args *FilterArgs) flags() []cli.Flag {
 		&cli.StringFlag{
 			Name:        "providers",
 			Destination: &args.Providers,
-			Usage:       `Providers to enable (comma separated list); default is all. Can exclude individual providers from default by adding '"_exclude_from_defaults": "true"' to the credentials file for a provider`,
+			Usage:       `Providers to enable (comma separated list of names, globs, re:regex, type=TYPE, -exclusions or @file); default is all. Can exclude individual providers from default by adding '"_exclude_from_defaults": "true"' to the credentials file for a provider`,
 			Value:       "",
 		},
 		&cli.StringFlag{
 			Name:        "domains",
 			Destination: &args.Domains,
-			Usage:       `Comma separated list of domain names to include`,
+			Usage:       `Comma separated list of domain names to include (names, globs, re:regex, -exclusions or @file)`,
+			Value:       "",
+		},
+		&cli.StringFlag{
+			Name:        "select",
+			Destination: &args.Select,
+			Usage:       `Only include domains whose D() metadata matches (comma separated list of key=value, -key=value or @file)`,
 			Value:       "",
 		},
 	}
 }
 
 // domainInList takes a domain and a list of domains and returns true if the
-// domain is in the list, accounting for wildcards and tags.
+// domain is in the list, accounting for globs and tags. The part before the
+// "!" is a glob that matches the domain name ("*" also matches dots). A
+// domain with a tag is only matched by an item with a matching tag, or by
+// an untagged glob ("*", "*.example.com"); an empty tag is the same as no
+// tag.
 func domainInList(domain string, list []string) bool {
+	dom, tag, isTagged := strings.Cut(domain, "!")
 	for _, item := range list {
-		if item == domain {
-			return true
+		pattern, patternTag, isPatternTagged := strings.Cut(item, "!")
+		if !zoneglob.Match(pattern, dom) {
+			continue
 		}
-		if strings.HasPrefix(item, "*") && strings.HasSuffix(domain, item[1:]) {
-			return true
-		}
-		filterDom, filterTag, isFilterTagged := strings.Cut(item, "!")
-		splitDom, domainTag, isDomainTagged := strings.Cut(domain, "!")
-		if splitDom == filterDom {
-			if isDomainTagged {
-				if filterTag == "*" {
-					return true
-				}
-				if domainTag == "" && !isFilterTagged {
-					// domain example.com! == filter example.com
-					return true
-				}
-				if isFilterTagged && domainTag == filterTag {
-					return true
-				}
-			}
-			if isFilterTagged {
-				if filterTag == "" && !isDomainTagged {
-					// filter example.com! == domain example.com
-					return true
-				}
+		if isPatternTagged && patternTag != "" {
+			if isTagged && zoneglob.Match(patternTag, tag) {
+				return true
 			}
+		} else if !isTagged || tag == "" || (!isPatternTagged && isGlob(pattern)) {
+			return true
 		}
 	}
 	return false
 }
+
+// isGlob reports whether pattern has any glob characters.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
@@ -108,6 +108,30 @@ func Test_domainInList(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "starMatchesTagged",
+			args: args{
+				domain: "example.com!inside",
+				list:   []string{"*"},
+			},
+			want: true,
+		},
+		{
+			name: "globMatchesTagged",
+			args: args{
+				domain: "a.example.com!inside",
+				list:   []string{"*.example.com"},
+			},
+			want: true,
+		},
+		{
+			name: "emptyTagGlobNotMatchTagged",
+			args: args{
+				domain: "example.com!inside",
+				list:   []string{"*!"},
+			},
+			want: false,
+		},
 		{
 			name: "domainTaggedNoMatch",
 			args: args{
@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"sync"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+// correctionsRunner prints (preview) or runs (push) the corrections of
+// each zone.  This is PHASE 3 of prun.
+type correctionsRunner struct {
+	args        PPreviewArgs
+	cfg         *models.DNSConfig
+	push        bool
+	interactive bool
+	notifier    notifications.Notifier
+	report      string
+	status      *runStatus
+	zresults    *zoneResults
+}
+
+// zoneOutcome is what PHASE 3 did for one or more zones.
+type zoneOutcome struct {
+	totalCorrections int
+	reportItems      []*ReportItem
+	changesItems     []*ChangesItem
+	anyErrors        bool
+}
+
+func (o *zoneOutcome) add(other zoneOutcome) {
+	o.totalCorrections += other.totalCorrections
+	o.reportItems = append(o.reportItems, other.reportItems...)
+	o.changesItems = append(o.changesItems, other.changesItems...)
+	o.anyErrors = o.anyErrors || other.anyErrors
+}
+
+// runAll processes the zones.  Zones whose providers all support
+// concurrency (and only those, whatever --cmode says) may be processed
+// concurrently, at most maxParallel at a time.  The output of each zone is
+// kept together and the zones are printed in order.
+func (r *correctionsRunner) runAll(ctx context.Context, zones []*models.DomainConfig, maxParallel int, out printer.CLI) zoneOutcome {
+	var total zoneOutcome
+
+	// Prompts must be answered in order. Previews are quick.
+	if maxParallel <= 1 || !r.push || r.interactive {
+		for _, zone := range zones {
+			total.add(r.run(ctx, zone, out))
+		}
+		return total
+	}
+
+	// Each zone writes to its own buffer. The buffers are printed in the
+	// order of the zones, each as soon as it (and all the zones before it)
+	// are done.
+	type slot struct {
+		buf     bytes.Buffer
+		outcome zoneOutcome
+		done    chan struct{}
+	}
+	slots := make([]*slot, len(zones))
+	for i := range slots {
+		slots[i] = &slot{done: make(chan struct{})}
+	}
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		for _, s := range slots {
+			<-s.done
+			out.Printf("%s", s.buf.String())
+		}
+	}()
+
+	r.notifier = &lockedNotifier{n: r.notifier}
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, zone := range zones {
+		s := slots[i]
+		zout := &printer.ConsolePrinter{Writer: &s.buf, Verbose: printer.DefaultPrinter.Verbose}
+		sem <- struct{}{}
+		if !allConcur(zone) {
+			// Counts towards maxParallel, but never runs concurrently with
+			// other zones of the same provider.
+			s.outcome = r.run(ctx, zone, zout)
+			close(s.done)
+			<-sem
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.outcome = r.run(ctx, zone, zout)
+			close(s.done)
+		}()
+	}
+	wg.Wait()
+	<-flushed
+
+	for _, s := range slots {
+		total.add(s.outcome)
+	}
+	return total
+}
+
+// run prints or runs the corrections of one zone.
+func (r *correctionsRunner) run(ctx context.Context, zone *models.DomainConfig, out printer.CLI) zoneOutcome {
+	var o zoneOutcome
+	args := r.args
+	providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
+
+	if ctx.Err() != nil {
+		// Interrupted. Do not start another zone.
+		for _, provider := range providersToProcess {
+			r.status.record(zone.GetUniqueName(), provider.Name, ctx.Err())
+		}
+		o.anyErrors = true
+		return o
+	}
+	out.StartDomain(zone.GetUniqueName())
+
+	// Process DNS provider changes:
+	for _, provider := range zone.DNSProviderInstances {
+		skip := skipProvider(provider.Name, providersToProcess)
+		out.StartDNSProvider(provider.Name, skip)
+		if skip {
+			continue
+		}
+		corrections := zone.GetCorrections(provider.Name)
+		numActions := zone.GetChangeCount(provider.Name)
+		o.totalCorrections += numActions
+		out.EndProvider2(provider.Name, numActions)
+		o.reportItems = append(o.reportItems, genReportItem(zone.Name, corrections, provider.Name))
+		if args.Changes != "" {
+			o.changesItems = append(o.changesItems, genRecordsChangesItem(zone, corrections, provider.Name, r.zresults))
+		}
+		if err := r.status.skip(provider.Name); r.push && numActions > 0 && err != nil {
+			out.Errorf("Not changing %s at %s: %s\n", zone.GetUniqueName(), provider.Name, err)
+			r.status.record(zone.GetUniqueName(), provider.Name, err)
+			o.anyErrors = true
+			continue
+		}
+		if r.push && args.Journal != "" && numActions > 0 {
+			// Never change a zone without a snapshot to roll back to.
+			fname, err := journalZone(args.Journal, r.cfg, zone, provider, r.zresults, corrections)
+			if err != nil {
+				out.Errorf("Not changing %s at %s: %s\n", zone.GetUniqueName(), provider.Name, err)
+				o.anyErrors = true
+				continue
+			}
+			out.Printf("Journal: %s\n", fname)
+		}
+		pctx, cancel := r.status.context(ctx, provider.Name)
+		failed := pprintOrRunCorrections(pctx, zone.Name, provider.Name, corrections, out, r.push, r.interactive, r.notifier, r.report)
+		r.status.recordCorrections(pctx, zone.GetUniqueName(), provider.Name, failed)
+		cancel()
+		o.anyErrors = cmp.Or(o.anyErrors, failed)
+	}
+
+	// Process Registrar changes:
+	skip := skipProvider(zone.RegistrarInstance.Name, providersToProcess)
+	out.StartRegistrar(zone.RegistrarName, !skip)
+	if skip {
+		corrections := zone.GetCorrections(zone.RegistrarInstance.Name)
+		numActions := zone.GetChangeCount(zone.RegistrarInstance.Name)
+		out.EndProvider2(zone.RegistrarName, numActions)
+		o.totalCorrections += numActions
+		o.reportItems = append(o.reportItems, genReportItem(zone.Name, corrections, zone.RegistrarName))
+		o.changesItems = append(o.changesItems, genNameserversChangesItem(zone, corrections))
+		if err := r.status.skip(zone.RegistrarInstance.Name); r.push && numActions > 0 && err != nil {
+			out.Errorf("Not changing the delegation of %s at %s: %s\n", zone.GetUniqueName(), zone.RegistrarInstance.Name, err)
+			r.status.record(zone.GetUniqueName(), zone.RegistrarInstance.Name, err)
+			o.anyErrors = true
+			return o
+		}
+		rctx, cancel := r.status.context(ctx, zone.RegistrarInstance.Name)
+		failed := pprintOrRunCorrections(rctx, zone.Name, zone.RegistrarInstance.Name, corrections, out, r.push, r.interactive, r.notifier, r.report)
+		r.status.recordCorrections(rctx, zone.GetUniqueName(), zone.RegistrarInstance.Name, failed)
+		cancel()
+		o.anyErrors = cmp.Or(o.anyErrors, failed)
+	}
+	return o
+}
+
+// lockedNotifier makes a Notifier safe for concurrent use.
+type lockedNotifier struct {
+	mu sync.Mutex
+	n  notifications.Notifier
+}
+
+func (l *lockedNotifier) Notify(domain, provider, message string, err error, preview bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.n.Notify(domain, provider, message, err, preview)
+}
+
+func (l *lockedNotifier) Done() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.n.Done()
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+func Test_correctionsRunner_runAll(t *testing.T) {
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	ran := map[string][]int{}
+
+	// providerType is a DNS provider that supports concurrency (BIND) or
+	// not (HEXONET).
+	mkZone := func(name, providerType string, delay time.Duration) *models.DomainConfig {
+		zone := &models.DomainConfig{
+			Name:                 name,
+			Metadata:             map[string]string{},
+			RegistrarInstance:    &models.RegistrarInstance{ProviderBase: models.ProviderBase{Name: "reg", ProviderType: "NONE"}},
+			DNSProviderInstances: []*models.DNSProviderInstance{{ProviderBase: models.ProviderBase{Name: "dsp", ProviderType: providerType, IsDefault: true}}},
+		}
+		zone.UpdateSplitHorizonNames()
+		var corrections []*models.Correction
+		for i := range 2 {
+			corrections = append(corrections, &models.Correction{
+				Msg: fmt.Sprintf("%s change %d", name, i),
+				F: func() error {
+					n := running.Add(1)
+					for {
+						p := peak.Load()
+						if n <= p || peak.CompareAndSwap(p, n) {
+							break
+						}
+					}
+					time.Sleep(delay)
+					running.Add(-1)
+					mu.Lock()
+					ran[name] = append(ran[name], i)
+					mu.Unlock()
+					return nil
+				},
+			})
+		}
+		zone.StoreCorrections("dsp", corrections)
+		zone.IncrementChangeCount("dsp", len(corrections))
+		return zone
+	}
+
+	// The first zones are the slowest, so they finish last.
+	zones := []*models.DomainConfig{
+		mkZone("a.com", "BIND", 40*time.Millisecond),
+		mkZone("b.com", "BIND", 20*time.Millisecond),
+		mkZone("c.com", "BIND", 10*time.Millisecond),
+		mkZone("d.com", "HEXONET", 1*time.Millisecond), // Must run serially.
+	}
+
+	notifier, err := notifications.Init(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	r := &correctionsRunner{
+		push:     true,
+		notifier: notifier,
+		status:   newRunStatus(providerTimeouts{}),
+		zresults: &zoneResults{},
+	}
+	outcome := r.runAll(context.Background(), zones, 2, &printer.ConsolePrinter{Writer: &buf})
+
+	if outcome.totalCorrections != 8 || outcome.anyErrors {
+		t.Errorf("outcome: %+v", outcome)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency: got %d, want 2", p)
+	}
+	for _, zone := range zones {
+		if got := ran[zone.Name]; len(got) != 2 || got[0] != 0 || got[1] != 1 {
+			t.Errorf("%s: corrections ran in order %v", zone.Name, got)
+		}
+	}
+
+	// The output is grouped by zone, in the order of the zones.
+	var headings []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "*****") {
+			headings = append(headings, strings.TrimPrefix(line, "******************** Domain: "))
+		}
+	}
+	if got := strings.Join(headings, " "); got != "a.com b.com c.com d.com" {
+		t.Errorf("zone order: got %q", got)
+	}
+	if !strings.Contains(buf.String(), "#1: a.com change 0\nSUCCESS!\n#2: a.com change 1\nSUCCESS!\n") {
+		t.Errorf("a.com output is not grouped:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/providers"
+	"github.com/StackExchange/dnscontrol/v4/providers/bind"
+)
+
+// "preview --existing-from DIR" reads the existing records of each zone
+// from snapshot files instead of asking the provider.  This permits a real
+// preview where no provider credentials are available (for example, a CI
+// job that runs on every PR).  The provider is still used to generate the
+// corrections.
+//
+// The snapshot of zone example.com at the provider named "myr53" (the key
+// in creds.json) is the first of these files that exists:
+//
+//	DIR/myr53/example.com.json   (IR JSON, as written by get-zones --format=json)
+//	DIR/myr53/example.com.zone   (BIND zonefile format)
+//	DIR/example.com.json
+//	DIR/example.com.zone
+//
+// For split horizon zones the unique name (example.com!tag) is tried
+// before the zone's name.  "get-zones --out-dir DIR" writes snapshots in
+// this layout.  A zone without a snapshot is an error: an empty snapshot
+// ("[]") must be written for a zone that does not exist yet.
+
+// snapshotExtensions lists the snapshot file formats, in order of preference.
+var snapshotExtensions = []string{".json", ".zone"}
+
+// snapshotProvider wraps a DNS provider.  The existing records are read
+// from snapshots; corrections are generated by the provider.
+type snapshotProvider struct {
+	providers.DNSServiceProvider
+	dir  string // The snapshot directory.
+	name string // The provider's name in creds.json.
+}
+
+// snapshotRegistrar replaces a registrar when running offline. It never
+// reports any corrections.
+type snapshotRegistrar struct{}
+
+func (snapshotRegistrar) GetRegistrarCorrections(*models.DomainConfig) ([]*models.Correction, error) {
+	return nil, nil
+}
+
+// useSnapshots replaces the providers of every zone in cfg so that the
+// existing records are read from snapshots in dir.
+func useSnapshots(cfg *models.DNSConfig, dir string) error {
+	if st, err := os.Stat(dir); err != nil {
+		return err
+	} else if !st.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+
+	wrapped := map[string]*snapshotProvider{}
+	for _, zone := range cfg.Domains {
+		zone.RegistrarInstance.Driver = snapshotRegistrar{}
+		for _, pInst := range zone.DNSProviderInstances {
+			if wrapped[pInst.Name] == nil {
+				wrapped[pInst.Name] = &snapshotProvider{DNSServiceProvider: pInst.Driver, dir: dir, name: pInst.Name}
+			}
+			pInst.Driver = wrapped[pInst.Name]
+		}
+	}
+	return nil
+}
+
+// snapshotFile returns the name of the snapshot file for a zone, or "" if
+// there is none.
+func (sp *snapshotProvider) snapshotFile(names ...string) (string, error) {
+	for _, d := range []string{filepath.Join(sp.dir, sp.name), sp.dir} {
+		for _, n := range names {
+			if n == "" {
+				continue
+			}
+			for _, ext := range snapshotExtensions {
+				fname := filepath.Join(d, n+ext)
+				if _, err := os.Stat(fname); err == nil {
+					return fname, nil
+				} else if !errors.Is(err, os.ErrNotExist) {
+					return "", err
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// readSnapshot reads the records in a snapshot file.
+func readSnapshot(fname string, domain string) (models.Records, error) {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(fname, ".zone") {
+		return bind.ParseZoneContents(string(b), domain, fname)
+	}
+
+	// get-zones --out-dir writes a list of records. Without --out-dir, it
+	// writes an object with the records of each zone: {"zone": [...]}.
+	var recs models.Records
+	if trimmed := strings.TrimSpace(string(b)); strings.HasPrefix(trimmed, "{") {
+		var all map[string]models.Records
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, fmt.Errorf("parsing snapshot %q: %w", fname, err)
+		}
+		var ok bool
+		if recs, ok = all[domain]; !ok {
+			return nil, fmt.Errorf("snapshot %q has no records for zone %q", fname, domain)
+		}
+	} else if err := json.Unmarshal(b, &recs); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %q: %w", fname, err)
+	}
+	// The FQDN is not stored in JSON. Recompute it.
+	for _, rec := range recs {
+		rec.SetLabel(rec.GetLabel(), domain)
+	}
+	return recs, nil
+}
+
+// GetZoneRecords returns the records in the zone's snapshot.  A zone
+// without a snapshot is an error, rather than a zone that would be
+// re-created from scratch.
+func (sp *snapshotProvider) GetZoneRecords(domain string, meta map[string]string) (models.Records, error) {
+	fname, err := sp.snapshotFile(meta[models.DomainUniqueName], domain)
+	if err != nil {
+		return nil, err
+	}
+	if fname == "" {
+		return nil, fmt.Errorf("no snapshot of zone %q for provider %q in %s (write an empty snapshot, [], for a new zone)", domain, sp.name, sp.dir)
+	}
+	return readSnapshot(fname, domain)
+}
+
+// GetNameservers returns the NS records at the apex of the zone's snapshot.
+func (sp *snapshotProvider) GetNameservers(domain string) ([]*models.Nameserver, error) {
+	recs, err := sp.GetZoneRecords(domain, nil)
+	if err != nil {
+		return nil, err
+	}
+	var nss []string
+	for _, rec := range recs {
+		if rec.Type == "NS" && rec.GetLabel() == "@" {
+			nss = append(nss, rec.GetTargetField())
+		}
+	}
+	return models.ToNameserversStripTD(nss)
+}
+
+// ListZones lists the zones that have a snapshot.
+func (sp *snapshotProvider) ListZones() ([]string, error) {
+	var zones []string
+	for _, d := range []string{filepath.Join(sp.dir, sp.name), sp.dir} {
+		entries, err := os.ReadDir(d)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			if e.IsDir() || !slices.Contains(snapshotExtensions, ext) {
+				continue
+			}
+			// Strip the tag (if any). ListZones returns zone names.
+			name, _, _ := strings.Cut(strings.TrimSuffix(e.Name(), ext), "!")
+			if !slices.Contains(zones, name) {
+				zones = append(zones, name)
+			}
+		}
+	}
+	return zones, nil
+}
+
+// EnsureZoneExists is never called since zones are not created in preview.
+// It is implemented so that missing zones are reported the same as when
+// running online.
+func (sp *snapshotProvider) EnsureZoneExists(domain string) error {
+	return fmt.Errorf("can not create zone %q: running with --existing-from", domain)
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_snapshotProvider(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Per-provider snapshots take precedence over the shared ones.
+	write("p1/example.com.zone", "$TTL 300\n@ IN A 1.2.3.4\n@ IN NS ns1.example.net.\nwww IN CNAME @\n")
+	write("example.com.json", `[{"type": "A", "name": "@", "ttl": 300, "target": "5.6.7.8"}]`)
+	write("example.org.json", `[{"type": "TXT", "name": "foo", "ttl": 600, "target": "bar", "txtstrings": ["bar"]}]`)
+
+	p1 := &snapshotProvider{dir: dir, name: "p1"}
+	recs, err := p1.GetZoneRecords("example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || recs[2].GetLabelFQDN() != "www.example.com" {
+		t.Errorf("p1 example.com: got %v", recs)
+	}
+	nss, err := p1.GetNameservers("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nss) != 1 || nss[0].Name != "ns1.example.net" {
+		t.Errorf("p1 nameservers: got %v", nss)
+	}
+
+	p2 := &snapshotProvider{dir: dir, name: "p2"}
+	recs, err = p2.GetZoneRecords("example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].GetTargetField() != "5.6.7.8" || recs[0].GetLabelFQDN() != "example.com" {
+		t.Errorf("p2 example.com: got %v", recs)
+	}
+	recs, err = p2.GetZoneRecords("example.org", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].GetLabelFQDN() != "foo.example.org" || recs[0].TTL != 600 {
+		t.Errorf("p2 example.org: got %v", recs)
+	}
+
+	// A zone without a snapshot is an error.
+	if _, err := p2.GetZoneRecords("example.net", nil); err == nil {
+		t.Errorf("p2 example.net: no error for a missing snapshot")
+	}
+
+	// The output of get-zones --format=json without --out-dir.
+	write("p3/example.com.json", `{"example.com": [{"type": "A", "name": "www", "ttl": 300, "target": "192.0.2.1"}], "example.org": []}`)
+	p3 := &snapshotProvider{dir: dir, name: "p3"}
+	recs, err = p3.GetZoneRecords("example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].GetLabelFQDN() != "www.example.com" {
+		t.Errorf("p3 example.com: got %v", recs)
+	}
+	write("p3/example.net.json", `{"example.com": []}`)
+	if _, err := p3.GetZoneRecords("example.net", nil); err == nil {
+		t.Errorf("p3 example.net: no error for a snapshot of other zones")
+	}
+
+	zones, err := p1.ListZones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(zones)
+	if !slices.Equal(zones, []string{"example.com", "example.org"}) {
+		t.Errorf("ListZones: got %v", zones)
+	}
+}
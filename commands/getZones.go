@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/StackExchange/dnscontrol/v4/models"
@@ -52,6 +55,7 @@ FORMATS:
    --format=js        dnsconfig.js format (not perfect, just a decent first draft)
    --format=djs       js with disco commas (leading commas)
    --format=zone      BIND zonefile format
+   --format=json      JSON (the records as dnscontrol represents them internally)
    --format=tsv       TAB separated value (useful for AWK)
    --format=nameonly  Just print the zone names
 
@@ -65,12 +69,18 @@ The columns in --format=tsv are:
 
 The --ttl flag only applies to zone/js/djs formats.
 
+With --out-dir, each zone is written to its own file named
+DIR/credkey/zone.zone (or .json).  These files can be used as snapshots
+with "preview --existing-from DIR".  Only the zone and json formats may
+be used with --out-dir.
+
 EXAMPLES:
    dnscontrol get-zones myr53 ROUTE53 example.com
    dnscontrol get-zones gmain GANDI_V5 example.com other.com
    dnscontrol get-zones cfmain CLOUDFLAREAPI all
    dnscontrol get-zones --format=tsv bind BIND example.com
-   dnscontrol get-zones --format=djs --out=draft.js gcloud GCLOUD example.com`,
+   dnscontrol get-zones --format=djs --out=draft.js gcloud GCLOUD example.com
+   dnscontrol get-zones --format=json --out-dir=snapshots myr53 - all`,
 	}
 }())
 
@@ -128,6 +138,7 @@ type GetZoneArgs struct {
 	ZoneNames          []string // The zones to get
 	OutputFormat       string   // Output format
 	OutputFile         string   // Filename to send output ("" means stdout)
+	OutputDir          string   // Directory to write one snapshot file per zone
 	DefaultTTL         int      // default TTL for providers where it is unknown
 }
 
@@ -137,13 +148,18 @@ func (args *GetZoneArgs) flags() []cli.Flag {
 		Name:        "format",
 		Destination: &args.OutputFormat,
 		Value:       "zone",
-		Usage:       `Output format: js djs zone tsv nameonly`,
+		Usage:       `Output format: js djs zone json tsv nameonly`,
 	})
 	flags = append(flags, &cli.StringFlag{
 		Name:        "out",
 		Destination: &args.OutputFile,
 		Usage:       `Instead of stdout, write to this file`,
 	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "out-dir",
+		Destination: &args.OutputDir,
+		Usage:       `Write each zone to DIR/credkey/zone.zone (or .json), for use with preview --existing-from`,
+	})
 	flags = append(flags, &cli.IntFlag{
 		Name:        "ttl",
 		Destination: &args.DefaultTTL,
@@ -162,6 +178,9 @@ func GetZone(args GetZoneArgs) error {
 	if err != nil {
 		return fmt.Errorf("failed GetZone LoadProviderConfigs(%q): %w", args.CredsFile, err)
 	}
+	if err := validateCreds(args.CredName, args.ProviderName, providerConfigs[args.CredName], map[string]bool{}); err != nil {
+		return err
+	}
 	provider, err := providers.CreateDNSProvider(args.ProviderName, providerConfigs[args.CredName], nil)
 	if err != nil {
 		return fmt.Errorf("failed GetZone CDP: %w", err)
@@ -180,6 +199,10 @@ func GetZone(args GetZoneArgs) error {
 		}
 	}
 
+	if args.OutputDir != "" {
+		return writeZoneSnapshots(provider, args, zones)
+	}
+
 	// first open output stream and print initial header (if applicable)
 	w := os.Stdout
 	if args.OutputFile != "" {
@@ -231,12 +254,14 @@ func GetZone(args GetZoneArgs) error {
 		z := prettyzone.PrettySort(recs, zoneName, 0, nil)
 		switch args.OutputFormat {
 		case "zone":
-			fmt.Fprintf(w, "$ORIGIN %s.\n", zoneName)
-			if err := prettyzone.WriteZoneFileRC(w, z.Records, zoneName, uint32(args.DefaultTTL), nil); err != nil {
+			if err := writeZoneFormat(w, z.Records, zoneName, uint32(args.DefaultTTL)); err != nil {
 				return err
 			}
 			fmt.Fprintln(w)
 
+		case "json":
+			// Written as a whole after this loop.
+
 		case "js", "djs":
 			sep := ",\n\t" // Commas at EOL
 			if args.OutputFormat == "djs" {
@@ -303,6 +328,66 @@ func GetZone(args GetZoneArgs) error {
 			return fmt.Errorf("format %q unknown", args.OutputFormat)
 		}
 	}
+
+	if args.OutputFormat == "json" {
+		// A JSON object: {"zone": [records...], ...}
+		all := make(map[string]models.Records, len(zones))
+		for i, zoneName := range zones {
+			all[zoneName] = zoneRecs[i]
+		}
+		b, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(b))
+	}
+	return nil
+}
+
+// writeZoneFormat writes the records of a zone in BIND zonefile format.
+func writeZoneFormat(w io.Writer, recs models.Records, zoneName string, defaultTTL uint32) error {
+	fmt.Fprintf(w, "$ORIGIN %s.\n", zoneName)
+	return prettyzone.WriteZoneFileRC(w, recs, zoneName, defaultTTL, nil)
+}
+
+// writeZoneSnapshots writes each zone to its own file in
+// args.OutputDir/args.CredName, in the layout read by
+// "preview --existing-from".
+func writeZoneSnapshots(provider providers.DNSServiceProvider, args GetZoneArgs, zones []string) error {
+	if args.OutputFormat != "zone" && args.OutputFormat != "json" {
+		return fmt.Errorf("--out-dir requires --format=zone or --format=json")
+	}
+	dir := filepath.Join(args.OutputDir, args.CredName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, zoneName := range zones {
+		recs, err := provider.GetZoneRecords(zoneName, nil)
+		if err != nil {
+			return fmt.Errorf("failed GetZone gzr: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if args.OutputFormat == "zone" {
+			z := prettyzone.PrettySort(recs, zoneName, 0, nil)
+			if err := writeZoneFormat(&buf, z.Records, zoneName, uint32(args.DefaultTTL)); err != nil {
+				return err
+			}
+		} else {
+			b, err := json.MarshalIndent(recs, "", "  ")
+			if err != nil {
+				return err
+			}
+			buf.Write(append(b, '\n'))
+		}
+
+		fname := filepath.Join(dir, zoneName+"."+args.OutputFormat)
+		if err := os.WriteFile(fname, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Wrote", fname)
+	}
 	return nil
 }
 
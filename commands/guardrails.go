@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+	"github.com/urfave/cli/v2"
+)
+
+// Guardrails are safety limits that stop push before any correction runs if
+// a zone would change "too much".  They protect against mistakes such as a
+// broken require() that removes most of a zone's records.
+//
+// The limits are set globally with CLI flags and may be overridden per
+// domain with D() metadata:
+//
+//	D("example.com", REG, {max_deletions: "50", protect_apex: "false"}, ...)
+
+// Metadata keys that override the guardrail flags for a domain.
+const (
+	metaMaxDeletions      = "max_deletions"
+	metaMaxChangedPercent = "max_changed_percent"
+	metaProtectApex       = "protect_apex"
+)
+
+// GuardArgs encapsulates the flags/args for the push safety limits.
+type GuardArgs struct {
+	MaxDeletions      int
+	MaxChangedPercent float64
+	ProtectApex       bool
+}
+
+func (args *GuardArgs) flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:        "max-deletions",
+			Destination: &args.MaxDeletions,
+			Usage:       `Abort push if more than this many records would be deleted from a zone (0 = no limit)`,
+		},
+		&cli.Float64Flag{
+			Name:        "max-changed-percent",
+			Destination: &args.MaxChangedPercent,
+			Usage:       `Abort push if more than this percentage of a zone's records would change (0 = no limit)`,
+		},
+		&cli.BoolFlag{
+			Name:        "protect-apex",
+			Destination: &args.ProtectApex,
+			Usage:       `Abort push if any NS, SOA or MX record at a zone's apex would be deleted`,
+		},
+	}
+}
+
+// zoneGuardLimits returns the limits for a zone: the global limits, with any
+// overrides from the domain's metadata.
+func zoneGuardLimits(zone *models.DomainConfig, global GuardArgs) (GuardArgs, error) {
+	limits := global
+	if v, ok := zone.Metadata[metaMaxDeletions]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return limits, fmt.Errorf("domain %q: invalid %s %q", zone.GetUniqueName(), metaMaxDeletions, v)
+		}
+		limits.MaxDeletions = n
+	}
+	if v, ok := zone.Metadata[metaMaxChangedPercent]; ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return limits, fmt.Errorf("domain %q: invalid %s %q", zone.GetUniqueName(), metaMaxChangedPercent, v)
+		}
+		limits.MaxChangedPercent = f
+	}
+	if v, ok := zone.Metadata[metaProtectApex]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return limits, fmt.Errorf("domain %q: invalid %s %q", zone.GetUniqueName(), metaProtectApex, v)
+		}
+		limits.ProtectApex = b
+	}
+	return limits, nil
+}
+
+// checkGuardrails returns a message for each limit that the changes in r
+// exceed.
+func checkGuardrails(zone *models.DomainConfig, providerName string, r *zonerecs.Result, limits GuardArgs) []string {
+	if limits == (GuardArgs{}) {
+		// No limits: don't bother listing the changes.
+		return nil
+	}
+	var violations []string
+	name := fmt.Sprintf("%s (%s)", zone.GetUniqueName(), providerName)
+
+	var deletions, changed int
+	var apex []string
+	for _, c := range r.RecordChanges() {
+		switch c.Type {
+		case diff2.CREATE:
+			changed += len(c.New)
+		case diff2.CHANGE:
+			changed += max(len(c.Old), len(c.New))
+			if len(c.Old) > len(c.New) {
+				deletions += len(c.Old) - len(c.New)
+			}
+		case diff2.DELETE:
+			changed += len(c.Old)
+			deletions += len(c.Old)
+			if c.Key.NameFQDN == r.Desired.Name && isApexProtected(c.Key.Type) {
+				apex = append(apex, c.Key.Type)
+			}
+		}
+	}
+
+	if limits.MaxDeletions > 0 && deletions > limits.MaxDeletions {
+		violations = append(violations, fmt.Sprintf("%s: %d records would be deleted (%s=%d)", name, deletions, metaMaxDeletions, limits.MaxDeletions))
+	}
+	if limits.MaxChangedPercent > 0 && len(r.Existing) > 0 {
+		pct := float64(changed) * 100 / float64(len(r.Existing))
+		if pct > limits.MaxChangedPercent {
+			violations = append(violations, fmt.Sprintf("%s: %.1f%% of %d records would change (%s=%g)", name, pct, len(r.Existing), metaMaxChangedPercent, limits.MaxChangedPercent))
+		}
+	}
+	if limits.ProtectApex {
+		for _, t := range apex {
+			violations = append(violations, fmt.Sprintf("%s: apex %s record(s) would be deleted (%s=true)", name, t, metaProtectApex))
+		}
+	}
+	return violations
+}
+
+// checkDepopulateGuardrails returns a message for each limit that deleting
+// a zone (with --depopulate) exceeds. The zone is not in dnsconfig.js, so
+// only the global limits apply, and all its records would be deleted.
+func checkDepopulateGuardrails(providerName, zoneName string, limits GuardArgs) []string {
+	var violations []string
+	name := fmt.Sprintf("%s (%s)", zoneName, providerName)
+	if limits.MaxDeletions > 0 || (limits.MaxChangedPercent > 0 && limits.MaxChangedPercent < 100) {
+		violations = append(violations, fmt.Sprintf("%s: the zone and all its records would be deleted (--depopulate)", name))
+	}
+	if limits.ProtectApex {
+		violations = append(violations, fmt.Sprintf("%s: the zone and its apex records would be deleted (--depopulate, %s=true)", name, metaProtectApex))
+	}
+	return violations
+}
+
+func isApexProtected(rtype string) bool {
+	return rtype == "NS" || rtype == "SOA" || rtype == "MX"
+}
+
+// guardrailViolations checks every zone/provider that was gathered and
+// returns all the limits that were exceeded.
+func guardrailViolations(zones []*models.DomainConfig, filter string, global GuardArgs, zr *zoneResults) ([]string, error) {
+	var violations []string
+	for _, zone := range zones {
+		limits, err := zoneGuardLimits(zone, global)
+		if err != nil {
+			return nil, err
+		}
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, filter) {
+			if r := zr.get(zone, provider.Name); r != nil {
+				violations = append(violations, checkGuardrails(zone, provider.Name, r, limits)...)
+			}
+		}
+	}
+	return violations, nil
+}
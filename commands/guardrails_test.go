@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_checkGuardrails(t *testing.T) {
+	existing := models.Records{
+		testRecord("@", "MX", "mail.example.com.", 300),
+		testRecord("@", "A", "1.2.3.4", 300),
+		testRecord("a", "A", "1.2.3.4", 300),
+		testRecord("b", "A", "1.2.3.4", 300),
+	}
+	desired := models.Records{
+		testRecord("@", "A", "1.2.3.4", 300),
+		testRecord("b", "A", "5.6.7.8", 300),
+	}
+	dc := &models.DomainConfig{Name: "example.com", Records: desired}
+	dc.UpdateSplitHorizonNames()
+	changes, _, err := diff2.ByRecord(existing, dc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &zonerecs.Result{Existing: existing, Desired: dc, Changes: changes}
+
+	tests := []struct {
+		name   string
+		limits GuardArgs
+		want   []string
+	}{
+		{"noLimits", GuardArgs{}, nil},
+		{"deletionsOK", GuardArgs{MaxDeletions: 2}, nil},
+		{"deletions", GuardArgs{MaxDeletions: 1}, []string{"2 records would be deleted"}},
+		{"percentOK", GuardArgs{MaxChangedPercent: 75}, nil},
+		{"percent", GuardArgs{MaxChangedPercent: 50}, []string{"75.0% of 4 records"}},
+		{"apex", GuardArgs{ProtectApex: true}, []string{"apex MX"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkGuardrails(dc, "bind", r, tt.limits)
+			if len(got) != len(tt.want) {
+				t.Fatalf("checkGuardrails() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if !strings.Contains(got[i], tt.want[i]) {
+					t.Errorf("checkGuardrails()[%d] = %q, want it to contain %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_zoneGuardLimits(t *testing.T) {
+	global := GuardArgs{MaxDeletions: 5, MaxChangedPercent: 10}
+	dc := &models.DomainConfig{Name: "example.com", Metadata: map[string]string{
+		metaMaxDeletions: "50",
+		metaProtectApex:  "true",
+	}}
+	got, err := zoneGuardLimits(dc, global)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GuardArgs{MaxDeletions: 50, MaxChangedPercent: 10, ProtectApex: true}
+	if got != want {
+		t.Errorf("zoneGuardLimits() = %+v, want %+v", got, want)
+	}
+
+	dc.Metadata[metaMaxChangedPercent] = "lots"
+	if _, err := zoneGuardLimits(dc, global); err == nil {
+		t.Errorf("zoneGuardLimits() expected error for invalid %s", metaMaxChangedPercent)
+	}
+}
+
+func Test_checkDepopulateGuardrails(t *testing.T) {
+	tests := []struct {
+		limits GuardArgs
+		want   int
+	}{
+		{GuardArgs{}, 0},
+		{GuardArgs{MaxDeletions: 10}, 1},
+		{GuardArgs{MaxChangedPercent: 50}, 1},
+		{GuardArgs{MaxChangedPercent: 100}, 0},
+		{GuardArgs{ProtectApex: true}, 1},
+		{GuardArgs{MaxDeletions: 10, ProtectApex: true}, 2},
+	}
+	for _, tt := range tests {
+		got := checkDepopulateGuardrails("bind", "old.example.com", tt.limits)
+		if len(got) != tt.want {
+			t.Errorf("%+v: got %q, want %d violations", tt.limits, got, tt.want)
+		}
+		for _, v := range got {
+			if !strings.HasPrefix(v, "old.example.com (bind): ") {
+				t.Errorf("%+v: got %q", tt.limits, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package commands
+
+import "github.com/StackExchange/dnscontrol/v4/models"
+
+// testRecord returns a record of example.com. The target of an MX record
+// has preference 10.
+func testRecord(label, typ, target string, ttl uint32) *models.RecordConfig {
+	rc := &models.RecordConfig{Type: typ, TTL: ttl, Metadata: map[string]string{}}
+	rc.SetLabel(label, "example.com")
+	switch typ {
+	case "MX":
+		rc.SetTargetMX(10, target)
+	case "TXT":
+		rc.SetTargetTXT(target)
+	default:
+		rc.SetTarget(target)
+	}
+	return rc
+}
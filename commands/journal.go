@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+// The apply journal records the state of a zone immediately before push
+// changes it.  Each snapshot can be given to the "rollback" command to
+// restore the zone to that state.
+
+// JournalEntry is a snapshot of a zone at a provider, taken before push ran
+// corrections on it.
+type JournalEntry struct {
+	Time         time.Time         `json:"time"`
+	User         string            `json:"user"`
+	Host         string            `json:"host,omitempty"`
+	Domain       string            `json:"domain"`                  // The zone's name (no tag).
+	Metadata     map[string]string `json:"meta,omitempty"`          // The D() metadata (includes the unique name and tag).
+	Provider     string            `json:"provider"`                // The key in creds.json.
+	ProviderType string            `json:"provider_type"`           // BIND, ROUTE53, etc.
+	ProviderMeta json.RawMessage   `json:"provider_meta,omitempty"` // The NewDnsProvider() metadata.
+	Existing     models.Records    `json:"existing"`                // The records before the push.
+	Changes      []ChangesRecord   `json:"changes,omitempty"`       // What push was about to change.
+	Corrections  []string          `json:"corrections,omitempty"`
+}
+
+// journalUser returns the name of the user running dnscontrol.
+func journalUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+var journalFileUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeJournal writes a snapshot of the zone's existing records (as
+// gathered in r) to the directory dir and returns the file's name.
+func writeJournal(dir string, cfg *models.DNSConfig, zone *models.DomainConfig, provider *models.DNSProviderInstance, r *zonerecs.Result, corrections []*models.Correction) (string, error) {
+	now := time.Now().UTC()
+	host, _ := os.Hostname()
+	entry := JournalEntry{
+		Time:         now,
+		User:         journalUser(),
+		Host:         host,
+		Domain:       zone.Name,
+		Metadata:     zone.Metadata,
+		Provider:     provider.Name,
+		ProviderType: provider.ProviderType,
+		Existing:     r.Existing,
+		Changes:      genChangesRecords(r.RecordChanges()),
+	}
+	if p, ok := cfg.DNSProvidersByName[provider.Name]; ok {
+		entry.ProviderMeta = p.Metadata
+	}
+	for _, c := range corrections {
+		if c.F != nil {
+			entry.Corrections = append(entry.Corrections, stripColors(c.Msg))
+		}
+	}
+
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s_%s_%s.json",
+		now.Format("20060102T150405.000Z"),
+		journalFileUnsafe.ReplaceAllString(zone.GetUniqueName(), "_"),
+		journalFileUnsafe.ReplaceAllString(provider.Name, "_"),
+	)
+	fname := filepath.Join(dir, name)
+	return fname, os.WriteFile(fname, append(b, '\n'), 0o640)
+}
+
+// journalZone writes the snapshot of a zone/provider gathered during
+// PHASE 2.
+func journalZone(dir string, cfg *models.DNSConfig, zone *models.DomainConfig, provider *models.DNSProviderInstance, zr *zoneResults, corrections []*models.Correction) (string, error) {
+	r := zr.get(zone, provider.Name)
+	if r == nil {
+		return "", fmt.Errorf("no existing records were gathered")
+	}
+	fname, err := writeJournal(dir, cfg, zone, provider, r, corrections)
+	if err != nil {
+		return "", fmt.Errorf("writing journal: %w", err)
+	}
+	return fname, nil
+}
+
+// readJournal reads a snapshot written by writeJournal.
+func readJournal(fname string) (*JournalEntry, error) {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	entry := &JournalEntry{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil, fmt.Errorf("parsing journal %q: %w", fname, err)
+	}
+	if entry.Domain == "" || entry.Provider == "" {
+		return nil, fmt.Errorf("journal %q: missing domain or provider", fname)
+	}
+
+	// The FQDN is not stored in JSON. Recompute it.
+	for _, rec := range entry.Existing {
+		rec.SetLabel(rec.GetLabel(), entry.Domain)
+	}
+	return entry, nil
+}
+
+// domainConfig returns a DomainConfig whose desired records are the
+// snapshot's existing records.
+func (entry *JournalEntry) domainConfig() *models.DomainConfig {
+	dc := &models.DomainConfig{
+		Name:     entry.Domain,
+		Metadata: map[string]string{},
+		Records:  entry.Existing,
+	}
+	for k, v := range entry.Metadata {
+		dc.Metadata[k] = v
+	}
+	dc.UpdateSplitHorizonNames()
+	return dc
+}
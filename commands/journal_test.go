@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_journalRoundTrip(t *testing.T) {
+	existing := models.Records{
+		testRecord("@", "A", "1.2.3.4", 300),
+		testRecord("www", "CNAME", "example.com.", 300),
+	}
+
+	zone := &models.DomainConfig{Name: "example.com!inside", Metadata: map[string]string{}}
+	zone.UpdateSplitHorizonNames()
+	provider := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "bind", ProviderType: "BIND"}}
+	cfg := &models.DNSConfig{DNSProvidersByName: map[string]*models.DNSProviderConfig{}}
+
+	fname, err := writeJournal(t.TempDir(), cfg, zone, provider, &zonerecs.Result{Existing: existing}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := readJournal(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Domain != "example.com" || entry.Provider != "bind" || entry.ProviderType != "BIND" {
+		t.Errorf("got domain=%q provider=%q type=%q", entry.Domain, entry.Provider, entry.ProviderType)
+	}
+
+	dc := entry.domainConfig()
+	if got := dc.GetUniqueName(); got != "example.com!inside" {
+		t.Errorf("unique name: got %q", got)
+	}
+	if got, want := fingerprintRecords(dc.Records), fingerprintRecords(existing); got != want {
+		t.Errorf("records changed in the round trip: got %v want %v", dc.Records, existing)
+	}
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"cmp"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/bindserial"
@@ -18,8 +20,10 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
 	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
 	"github.com/StackExchange/dnscontrol/v4/pkg/rfc4183"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zoneglob"
 	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
 	"github.com/StackExchange/dnscontrol/v4/providers"
+	"github.com/gobwas/glob"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/exp/slices"
 	"golang.org/x/net/idna"
@@ -47,6 +51,7 @@ type PPreviewArgs struct {
 	GetDNSConfigArgs
 	GetCredentialsArgs
 	FilterArgs
+	GuardArgs
 	Notify            bool
 	WarnChanges       bool
 	ConcurMode        string
@@ -54,6 +59,17 @@ type PPreviewArgs struct {
 	DePopulate        bool
 	PopulateOnPreview bool
 	Report            string
+	Changes           string
+	ChangesFormat     string
+	OutPlan           string
+	Plan              string // Set by PPush.
+	Journal           string // Set by PPush.
+	MaxParallel       int    // Set by PPush.
+	ExistingFrom      string
+	ChangedSince      string
+	Types             string
+	Labels            string
+	Timeout           time.Duration
 	Full              bool
 }
 
@@ -69,6 +85,7 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 	flags := args.GetDNSConfigArgs.flags()
 	flags = append(flags, args.GetCredentialsArgs.flags()...)
 	flags = append(flags, args.FilterArgs.flags()...)
+	flags = append(flags, args.GuardArgs.flags()...)
 	flags = append(flags, &cli.BoolFlag{
 		Name:        "notify",
 		Destination: &args.Notify,
@@ -99,7 +116,7 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 	})
 	flags = append(flags, &cli.BoolFlag{
 		Name:        "depopulate",
-		Destination: &args.NoPopulate,
+		Destination: &args.DePopulate,
 		Usage:       `Delete unknown zones at provider (dangerous!)`,
 	})
 	flags = append(flags, &cli.BoolFlag{
@@ -126,12 +143,63 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 		Name:        "bindserial",
 		Destination: &bindserial.ForcedValue,
 		Usage:       `Force BIND serial numbers to this value (for reproducibility)`,
+		Action: func(ctx *cli.Context, v int64) error {
+			return bindserial.CheckForcedValue(v)
+		},
 	})
 	flags = append(flags, &cli.StringFlag{
 		Name:        "report",
 		Destination: &args.Report,
 		Usage:       `Generate a machine-parseable report of corrections.`,
 	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "changes",
+		Destination: &args.Changes,
+		Usage:       `Write a machine-parseable list of every change to this file.`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "changes-format",
+		Destination: &args.ChangesFormat,
+		Value:       "json",
+		Usage:       `Format of the --changes file: json, ndjson`,
+		Action: func(c *cli.Context, s string) error {
+			if !slices.Contains(changesFormats, s) {
+				fmt.Printf("%q is not a valid option for --changes-format.  Values are: %s\n", s, strings.Join(changesFormats, ", "))
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "out-plan",
+		Destination: &args.OutPlan,
+		Usage:       `Save the desired configuration and the state of each zone to this file (for use with push --plan)`,
+	})
+	flags = append(flags, &cli.DurationFlag{
+		Name:        "timeout",
+		Destination: &args.Timeout,
+		Usage:       `Give up on a zone if a provider takes longer than this to gather its data or to run its corrections (0 = no limit)`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "existing-from",
+		Destination: &args.ExistingFrom,
+		Usage:       `Read the existing records of each zone from snapshots in this directory instead of from the providers (preview only)`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "changed-since",
+		Destination: &args.ChangedSince,
+		Usage:       `Only process the zones whose desired configuration changed since this git revision`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "types",
+		Destination: &args.Types,
+		Usage:       `Only change records of these types (comma separated list); other records are left as they are`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "labels",
+		Destination: &args.Labels,
+		Usage:       `Only change records whose label matches one of these globs (comma separated list); other records are left as they are`,
+	})
 	return flags
 }
 
@@ -151,6 +219,9 @@ var _ = cmd(catMain, func() *cli.Command {
 type PPushArgs struct {
 	PPreviewArgs
 	Interactive bool
+	Plan        string
+	Journal     string
+	MaxParallel int
 }
 
 func (args *PPushArgs) flags() []cli.Flag {
@@ -160,6 +231,22 @@ func (args *PPushArgs) flags() []cli.Flag {
 		Destination: &args.Interactive,
 		Usage:       "Interactive. Confirm or Exclude each correction before they run",
 	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "plan",
+		Destination: &args.Plan,
+		Usage:       "Apply the plan saved by preview --out-plan. Refuse to run if any zone changed since then",
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "journal",
+		Destination: &args.Journal,
+		Usage:       "Before changing a zone, save a snapshot of its records to this directory (for use with rollback)",
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "max-parallel",
+		Destination: &args.MaxParallel,
+		Value:       1,
+		Usage:       "Run the corrections of up to this many zones at the same time (only zones whose providers all support concurrency)",
+	})
 	return flags
 }
 
@@ -170,6 +257,9 @@ func PPreview(args PPreviewArgs) error {
 
 // PPush implements the push subcommand.
 func PPush(args PPushArgs) error {
+	args.PPreviewArgs.Plan = args.Plan
+	args.PPreviewArgs.Journal = args.Journal
+	args.PPreviewArgs.MaxParallel = args.MaxParallel
 	return prun(args.PPreviewArgs, true, args.Interactive, printer.DefaultPrinter, args.Report)
 }
 
@@ -185,12 +275,53 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		printer.Println("WARNING: Please remove obsolete --diff2 flag. This will be an error in v5 or later. See https://github.com/StackExchange/dnscontrol/issues/2262")
 	}
 
-	out.PrintfIf(fullMode, "Reading dnsconfig.js or equiv.\n")
-	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err := args.FilterArgs.prepare(); err != nil {
+		return err
+	}
+	if args.DePopulate && ((args.Domains != "" && args.Domains != "all") || args.Select != "" || args.ChangedSince != "") {
+		// The zones to delete are the ones that are not in dnsconfig.js,
+		// which can not be narrowed down by what they declare.
+		return errors.New("--depopulate can not be used with --domains, --select or --changed-since")
+	}
+	partial, err := partialPushConfig(args.Types, args.Labels)
+	if err != nil {
+		return err
+	}
+
+	var cfg *models.DNSConfig
+	var plan *SavedPlan
+	if args.Plan != "" {
+		out.PrintfIf(fullMode, "Reading plan %q\n", args.Plan)
+		plan, cfg, err = readSavedPlan(args.Plan)
+	} else {
+		out.PrintfIf(fullMode, "Reading dnsconfig.js or equiv.\n")
+		cfg, err = GetDNSConfig(args.GetDNSConfigArgs)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Find the zones that changed (before cfg is normalized):
+	var changed map[string]bool
+	if args.ChangedSince != "" {
+		if plan != nil {
+			return errors.New("--changed-since can not be used with --plan")
+		}
+		changed, err = changedZones(args.GetDNSConfigArgs, cfg, args.ChangedSince)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the desired config (before it is normalized) in the new plan:
+	var outPlan *SavedPlan
+	if args.OutPlan != "" {
+		outPlan, err = newSavedPlan(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
 	out.PrintfIf(fullMode, "Reading creds.json or equiv.\n")
 	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
 	if err != nil {
@@ -203,20 +334,52 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		return err
 	}
 
+	if args.ExistingFrom != "" {
+		if push || args.PopulateOnPreview {
+			return errors.New("--existing-from can only be used with preview")
+		}
+		out.Printf("Reading existing records from snapshots in %q. Registrars are not checked.\n", args.ExistingFrom)
+		if err := useSnapshots(cfg, args.ExistingFrom); err != nil {
+			return err
+		}
+	}
+
 	out.PrintfIf(fullMode, "Normalizing and validating 'desired'..\n")
 	errs := normalize.ValidateAndNormalizeConfig(cfg)
 	if PrintValidationErrors(errs) {
 		return errors.New("exiting due to validation errors")
 	}
 
+	timeouts, err := newProviderTimeouts(args.Timeout, providerConfigs)
+	if err != nil {
+		return err
+	}
+	status := newRunStatus(timeouts)
+	ctx, stop := interruptContext(out)
+	defer stop()
+
 	zcache := NewCmdZoneCache()
+	zresults := &zoneResults{}
 
 	// Loop over all (or some) zones:
-	zonesToProcess := whichZonesToProcess(cfg.Domains, args.Domains)
+	zonesToProcess := args.whichZones(cfg.Domains)
+	if changed != nil {
+		zonesToProcess = slices.DeleteFunc(slices.Clone(zonesToProcess), func(dc *models.DomainConfig) bool {
+			return !changed[dc.GetUniqueName()]
+		})
+		out.Printf("%d zone(s) changed since %s\n", len(zonesToProcess), args.ChangedSince)
+	}
+	if partial != nil {
+		for _, zone := range zonesToProcess {
+			c := *partial // Each zone compiles its own copy.
+			zone.Unmanaged = append(zone.Unmanaged, &c)
+		}
+	}
 	zonesSerial, zonesConcurrent := splitConcurrent(zonesToProcess, args.ConcurMode)
 
 	var totalCorrections int
 	var reportItems []*ReportItem
+	var changesItems []*ChangesItem
 	var anyErrors bool
 	var concurrentErrors atomic.Bool
 
@@ -230,7 +393,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 			out.PrintfIf(fullMode, "Concurrently checking for zone: %q\n", zone.Name)
 			go func(zone *models.DomainConfig) {
 				defer wg.Done()
-				if err := oneZonePopulate(zone, zcache); err != nil {
+				if err := oneZonePopulate(ctx, zone, zcache, status); err != nil {
 					concurrentErrors.Store(true)
 				}
 			}(zone)
@@ -238,7 +401,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		out.PrintfIf(fullMode, "SERIALLY checking for %d zone(s)\n", len(zonesSerial))
 		for _, zone := range zonesSerial {
 			out.PrintfIf(fullMode, "Serially checking for zone: %q\n", zone.Name)
-			if err := oneZonePopulate(zone, zcache); err != nil {
+			if err := oneZonePopulate(ctx, zone, zcache, status); err != nil {
 				anyErrors = true
 			}
 		}
@@ -246,6 +409,15 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		wg.Wait()
 		out.PrintfIf(fullMode && len(zonesConcurrent) > 0, "DONE\n")
 
+		if outPlan != nil {
+			outPlan.recordPopulate(zonesToProcess, args.Providers)
+		}
+		if plan != nil {
+			if err := plan.checkPopulate(zonesToProcess, args.Providers); err != nil {
+				return err
+			}
+		}
+
 		for _, zone := range zonesToProcess {
 			started := false // Do not emit noise when no provider has corrections.
 			providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
@@ -260,11 +432,22 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 				}
 				skip := skipProvider(provider.Name, providersToProcess)
 				out.StartDNSProvider(provider.Name, skip)
+				if err := status.skip(provider.Name); !skip && err != nil && (push || args.PopulateOnPreview) {
+					out.Errorf("Not creating %s at %s: %s\n", zone.GetUniqueName(), provider.Name, err)
+					status.record(zone.GetUniqueName(), provider.Name, err)
+					anyErrors = true
+					continue
+				}
 				if !skip {
 					totalCorrections += len(corrections)
 					out.EndProvider2(provider.Name, len(corrections))
 					reportItems = append(reportItems, genReportItem(zone.Name, corrections, provider.Name))
-					anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push || args.PopulateOnPreview, interactive, notifier, report))
+					changesItems = append(changesItems, genChangesItem("populate", zone.GetUniqueName(), corrections, provider.Name))
+					pctx, cancel := status.context(ctx, provider.Name)
+					failed := pprintOrRunCorrections(pctx, zone.Name, provider.Name, corrections, out, push || args.PopulateOnPreview, interactive, notifier, report)
+					status.recordCorrections(pctx, zone.GetUniqueName(), provider.Name, failed)
+					cancel()
+					anyErrors = cmp.Or(anyErrors, failed)
 				}
 			}
 		}
@@ -278,7 +461,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		out.PrintfIf(fullMode, "Concurrently gathering: %q\n", zone.Name)
 		go func(zone *models.DomainConfig, args PPreviewArgs, zcache *cmdZoneCache) {
 			defer wg.Done()
-			if err := oneZone(zone, args); err != nil {
+			if err := oneZone(ctx, zone, args, zresults, status); err != nil {
 				concurrentErrors.Store(true)
 			}
 		}(zone, args, zcache)
@@ -286,7 +469,7 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	out.Printf("SERIALLY gathering %d zone(s)\n", len(zonesSerial))
 	for _, zone := range zonesSerial {
 		out.Printf("Serially Gathering: %q\n", zone.Name)
-		if err := oneZone(zone, args); err != nil {
+		if err := oneZone(ctx, zone, args, zresults, status); err != nil {
 			anyErrors = true
 		}
 	}
@@ -295,36 +478,98 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	out.PrintfIf(len(zonesConcurrent) > 0, "DONE\n")
 	anyErrors = cmp.Or(anyErrors, concurrentErrors.Load())
 
-	// Now we know what to do, print or do the tasks.
-	out.PrintfIf(fullMode, "PHASE 3: CORRECTIONS\n")
-	for _, zone := range zonesToProcess {
-		out.StartDomain(zone.GetUniqueName())
-
-		// Process DNS provider changes:
-		providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
-		for _, provider := range zone.DNSProviderInstances {
-			skip := skipProvider(provider.Name, providersToProcess)
-			out.StartDNSProvider(provider.Name, skip)
-			if !skip {
-				corrections := zone.GetCorrections(provider.Name)
-				numActions := zone.GetChangeCount(provider.Name)
-				totalCorrections += numActions
-				out.EndProvider2(provider.Name, numActions)
-				reportItems = append(reportItems, genReportItem(zone.Name, corrections, provider.Name))
-				anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push, interactive, notifier, report))
+	// Find the zones that exist at a provider but are not in dnsconfig.js:
+	var deletions []depopulateZone
+	if args.DePopulate {
+		out.PrintfIf(fullMode, "PHASE 2b: CHECKING for unknown zones\n")
+		declared := declaredZones(cfg.Domains)
+		for _, provider := range uniqueProviders(zonesToProcess, args.Providers) {
+			pctx, cancel := status.context(ctx, provider.Name)
+			zones, err := zonesToDepopulate(pctx, provider, declared, providerConfigs[provider.Name], zcache)
+			cancel()
+			if err != nil {
+				out.Errorf("%s\n", err)
+				anyErrors = true
+			}
+			for _, zoneName := range zones {
+				deletions = append(deletions, depopulateZone{provider, zoneName})
 			}
 		}
+	}
 
-		// Process Registrar changes:
-		skip := skipProvider(zone.RegistrarInstance.Name, providersToProcess)
-		out.StartRegistrar(zone.RegistrarName, !skip)
-		if skip {
-			corrections := zone.GetCorrections(zone.RegistrarInstance.Name)
-			numActions := zone.GetChangeCount(zone.RegistrarInstance.Name)
-			out.EndProvider2(zone.RegistrarName, numActions)
+	if outPlan != nil {
+		outPlan.recordExisting(zonesToProcess, args.Providers, zresults)
+		outPlan.recordDepopulate(deletions)
+		if err := outPlan.write(args.OutPlan); err != nil {
+			return fmt.Errorf("could not write plan: %w", err)
+		}
+	}
+	if plan != nil {
+		if err := plan.checkExisting(zonesToProcess, args.Providers, zresults); err != nil {
+			return err
+		}
+		if err := plan.checkDepopulate(deletions); err != nil {
+			return err
+		}
+	}
+
+	// Enforce the safety limits before any corrections are run:
+	violations, err := guardrailViolations(zonesToProcess, args.Providers, args.GuardArgs, zresults)
+	if err != nil {
+		return err
+	}
+	for _, d := range deletions {
+		violations = append(violations, checkDepopulateGuardrails(d.provider.Name, d.zone, args.GuardArgs)...)
+	}
+	for _, v := range violations {
+		out.Warnf("%s\n", v)
+	}
+	if push && len(violations) != 0 {
+		return errors.New("push aborted: safety limits exceeded (see --max-deletions, --max-changed-percent, --protect-apex)")
+	}
+
+	// Now we know what to do, print or do the tasks.
+	out.PrintfIf(fullMode, "PHASE 3: CORRECTIONS\n")
+	runner := &correctionsRunner{
+		args:        args,
+		cfg:         cfg,
+		push:        push,
+		interactive: interactive,
+		notifier:    notifier,
+		report:      report,
+		status:      status,
+		zresults:    zresults,
+	}
+	outcome := runner.runAll(ctx, zonesToProcess, args.MaxParallel, out)
+	totalCorrections += outcome.totalCorrections
+	reportItems = append(reportItems, outcome.reportItems...)
+	changesItems = append(changesItems, outcome.changesItems...)
+	anyErrors = cmp.Or(anyErrors, outcome.anyErrors)
+
+	// Delete zones that exist at a provider but are not in dnsconfig.js:
+	if args.DePopulate {
+		out.PrintfIf(fullMode, "PHASE 4: DELETING unknown zones\n")
+		for _, d := range deletions {
+			provider, zoneName := d.provider, d.zone
+			if err := status.skip(provider.Name); err != nil && push {
+				out.Errorf("Not deleting %s at %s: %s\n", zoneName, provider.Name, err)
+				status.record(zoneName, provider.Name, err)
+				anyErrors = true
+				continue
+			}
+			corrections := generateDepopulateCorrections(provider, zoneName)
+			numActions := countActions(corrections)
+			out.StartDomain(zoneName)
+			out.StartDNSProvider(provider.Name, false)
 			totalCorrections += numActions
-			reportItems = append(reportItems, genReportItem(zone.Name, corrections, zone.RegistrarName))
-			anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, zone.RegistrarInstance.Name, corrections, out, push, interactive, notifier, report))
+			out.EndProvider2(provider.Name, numActions)
+			reportItems = append(reportItems, genReportItem(zoneName, corrections, provider.Name))
+			changesItems = append(changesItems, genChangesItem("depopulate", zoneName, corrections, provider.Name))
+			pctx, cancel := status.context(ctx, provider.Name)
+			failed := pprintOrRunCorrections(pctx, zoneName, provider.Name, corrections, out, push, interactive, notifier, report)
+			status.recordCorrections(pctx, zoneName, provider.Name, failed)
+			cancel()
+			anyErrors = cmp.Or(anyErrors, failed)
 		}
 	}
 
@@ -333,11 +578,19 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	}
 	rfc4183.PrintWarning()
 	notifier.Done()
+	status.print(out, fullMode)
 	out.Printf("Done. %d corrections.\n", totalCorrections)
 	err = writeReport(report, reportItems)
 	if err != nil {
 		return errors.New("could not write report")
 	}
+	err = writeChanges(args.Changes, args.ChangesFormat, changesItems)
+	if err != nil {
+		return fmt.Errorf("could not write changes: %w", err)
+	}
+	if ctx.Err() != nil {
+		return errors.New("interrupted")
+	}
 	if anyErrors {
 		return errors.New("completed with errors")
 	}
@@ -347,29 +600,36 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	return nil
 }
 
-// func countActions(corrections []*models.Correction) int {
-//	r := 0
-//	for _, c := range corrections {
-//		if c.F != nil {
-//			r++
-//		}
-//	}
-//	return r
-//}
+// countActions returns the number of corrections that are actions (not
+// informational messages).
+func countActions(corrections []*models.Correction) int {
+	r := 0
+	for _, c := range corrections {
+		if c.F != nil {
+			r++
+		}
+	}
+	return r
+}
 
 // whichZonesToProcess takes a list of DomainConfigs and a filter string and
-// returns a list of DomainConfigs whose metadata[DomainUniqueName] matched the
-// filter. The filter string is a comma-separated list of domain names. If the
-// filter string is empty or "all", all domains are returned.
+// returns a list of DomainConfigs whose metadata[DomainUniqueName] (or other
+// metadata) matched the filter. The filter string is a selector (see
+// selector.go). If the filter string is empty or "all", all domains are
+// returned.
 func whichZonesToProcess(domains []*models.DomainConfig, filter string) []*models.DomainConfig {
 	if filter == "" || filter == "all" {
 		return domains
 	}
 
-	permitList := strings.Split(filter, ",")
+	sel, err := newSelector(filter, domainPattern)
+	if err != nil {
+		// Reported by FilterArgs.prepare().
+		return nil
+	}
 	var picked []*models.DomainConfig
 	for _, domain := range domains {
-		if domainInList(domain.GetUniqueName(), permitList) {
+		if sel.match(domain.GetUniqueName(), domain.Metadata, true) {
 			picked = append(picked, domain)
 		}
 	}
@@ -431,12 +691,20 @@ func optimizeOrder(zones []*models.DomainConfig) []*models.DomainConfig {
 	return zones
 }
 
-func oneZonePopulate(zone *models.DomainConfig, zc *cmdZoneCache) error {
+func oneZonePopulate(ctx context.Context, zone *models.DomainConfig, zc *cmdZoneCache, status *runStatus) error {
 	var errs []error
 	// Loop over all the providers configured for that zone:
 	for _, provider := range zone.DNSProviderInstances {
-		populateCorrections, err := generatePopulateCorrections(provider, zone.Name, zc)
+		if err := status.skip(provider.Name); err != nil {
+			status.record(zone.GetUniqueName(), provider.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		pctx, cancel := status.context(ctx, provider.Name)
+		populateCorrections, err := generatePopulateCorrections(pctx, provider, zone.Name, zc)
+		cancel()
 		if err != nil {
+			status.record(zone.GetUniqueName(), provider.Name, err)
 			errs = append(errs, err)
 		}
 		zone.StorePopulateCorrections(provider.Name, populateCorrections)
@@ -444,23 +712,36 @@ func oneZonePopulate(zone *models.DomainConfig, zc *cmdZoneCache) error {
 	return errors.Join(errs...)
 }
 
-func oneZone(zone *models.DomainConfig, args PPreviewArgs) error {
+func oneZone(ctx context.Context, zone *models.DomainConfig, args PPreviewArgs, zr *zoneResults, status *runStatus) error {
 	var errs []error
 	// Fix the parent zone's delegation: (if able/needed)
-	delegationCorrections, dcCount, err := generateDelegationCorrections(zone, zone.DNSProviderInstances, zone.RegistrarInstance)
+	rctx, cancel := status.context(ctx, zone.RegistrarInstance.Name)
+	delegationCorrections, dcCount, err := generateDelegationCorrections(rctx, zone, zone.DNSProviderInstances, zone.RegistrarInstance)
+	cancel()
 	if err != nil {
+		status.record(zone.GetUniqueName(), zone.RegistrarInstance.Name, err)
 		errs = append(errs, err)
+	} else {
+		zr.storeDelegation(zone, delegationCorrections)
 	}
 
 	// Loop over the (selected) providers configured for that zone:
 	providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
 	for _, provider := range providersToProcess {
+		if err := status.skip(provider.Name); err != nil {
+			status.record(zone.GetUniqueName(), provider.Name, err)
+			errs = append(errs, err)
+			continue
+		}
 		// Update the zone's records at the provider:
-		zoneCor, rep, actualChangeCount, err := generateZoneCorrections(zone, provider)
+		pctx, cancel := status.context(ctx, provider.Name)
+		zoneCor, rep, actualChangeCount, err := generateZoneCorrections(pctx, zone, provider, zr)
+		cancel()
 		zone.StoreCorrections(provider.Name, rep)
 		zone.StoreCorrections(provider.Name, zoneCor)
 		zone.IncrementChangeCount(provider.Name, actualChangeCount)
 		if err != nil {
+			status.record(zone.GetUniqueName(), provider.Name, err)
 			errs = append(errs, err)
 		}
 	}
@@ -471,30 +752,23 @@ func oneZone(zone *models.DomainConfig, args PPreviewArgs) error {
 	return errors.Join(errs...)
 }
 
+// whichProvidersToProcess returns the providers picked by filter, a
+// selector (see selector.go). If filter is empty, or only excludes
+// providers, it applies to the default providers.
 func whichProvidersToProcess(providers []*models.DNSProviderInstance, filter string) []*models.DNSProviderInstance {
 	if filter == "all" { // all
 		return providers
 	}
 
-	permitList := strings.Split(filter, ",")
-	var picked []*models.DNSProviderInstance
-
-	// Just the default providers:
-	if filter == "" {
-		for _, provider := range providers {
-			if provider.IsDefault {
-				picked = append(picked, provider)
-			}
-		}
-		return picked
+	sel, err := newSelector(filter, compilePattern)
+	if err != nil {
+		// Reported by FilterArgs.prepare().
+		return nil
 	}
-
-	// Just the exact matches:
+	var picked []*models.DNSProviderInstance
 	for _, provider := range providers {
-		for _, filterItem := range permitList {
-			if provider.Name == filterItem {
-				picked = append(picked, provider)
-			}
+		if sel.match(provider.Name, providerMeta(provider), provider.IsDefault) {
+			picked = append(picked, provider)
 		}
 	}
 	return picked
@@ -508,29 +782,34 @@ func skipProvider(name string, providers []*models.DNSProviderInstance) bool {
 
 func genReportItem(zname string, corrections []*models.Correction, pname string) *ReportItem {
 	// Only count the actions, not the messages.
-	cnt := 0
-	for _, cor := range corrections {
-		if cor.F != nil {
-			cnt++
-		}
-	}
-
 	r := ReportItem{
 		Domain:      zname,
-		Corrections: cnt,
+		Corrections: countActions(corrections),
 		Provider:    pname,
 	}
 	return &r
 }
 
-func pprintOrRunCorrections(zoneName string, providerName string, corrections []*models.Correction, out printer.CLI, push bool, interactive bool, notifier notifications.Notifier, report string) bool {
+// pprintOrRunCorrections prints the corrections and, if push is set, runs
+// them.  No correction is started after ctx is done.  A correction that is
+// running when ctx is cancelled is allowed to finish; one that is running
+// when ctx's deadline expires is abandoned.
+func pprintOrRunCorrections(ctx context.Context, zoneName string, providerName string, corrections []*models.Correction, out printer.CLI, push bool, interactive bool, notifier notifications.Notifier, report string) bool {
 	if len(corrections) == 0 {
 		return false
 	}
 	var anyErrors bool
 	cc := 0
 	cn := 0
-	for _, correction := range corrections {
+	for i, correction := range corrections {
+		if push && ctx.Err() != nil {
+			if n := countActions(corrections[i:]); n != 0 {
+				out.Warnf("%s: %d correction(s) not run for %s (%s)\n", ctx.Err(), n, zoneName, providerName)
+				anyErrors = true
+			}
+			break
+		}
+
 		// Print what we're about to do.
 		if correction.F == nil {
 			out.PrintReport(cn, correction)
@@ -549,7 +828,7 @@ func pprintOrRunCorrections(zoneName string, providerName string, corrections []
 		if correction.F != nil {
 			var err error
 			if push {
-				err = correction.F()
+				err = runCorrection(printer.WithPrinter(ctx, out), correction)
 				out.EndCorrection(err)
 				if err != nil {
 					anyErrors = true
@@ -566,6 +845,20 @@ func pprintOrRunCorrections(zoneName string, providerName string, corrections []
 	return anyErrors
 }
 
+// runCorrection runs a correction with ctx's deadline (if any), but
+// without ctx's cancellation.
+func runCorrection(ctx context.Context, correction *models.Correction) error {
+	rctx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		rctx, cancel = context.WithDeadline(rctx, deadline)
+		defer cancel()
+	}
+	err := correction.RunContext(rctx)
+	noteAbandoned(ctx, err)
+	return err
+}
+
 func writeReport(report string, reportItems []*ReportItem) error {
 	// No filename? No report.
 	if report == "" {
@@ -587,16 +880,16 @@ func writeReport(report string, reportItems []*ReportItem) error {
 	return nil
 }
 
-func generatePopulateCorrections(provider *models.DNSProviderInstance, zoneName string, zcache *cmdZoneCache) ([]*models.Correction, error) {
+func generatePopulateCorrections(ctx context.Context, provider *models.DNSProviderInstance, zoneName string, zcache *cmdZoneCache) ([]*models.Correction, error) {
 	lister, ok := provider.Driver.(providers.ZoneLister)
 	if !ok {
 		return nil, nil // We can't generate a list. No corrections are possible.
 	}
 
-	z, err := zcache.zoneList(provider.Name, lister)
+	z, err := zcache.zoneList(ctx, provider.Name, lister)
 	if err != nil {
-		errMsg := fmt.Sprintf("zoneList failed for %q: %s", provider.Name, err)
-		return []*models.Correction{{Msg: errMsg}}, errors.New(errMsg)
+		err = fmt.Errorf("zoneList failed for %q: %w", provider.Name, err)
+		return []*models.Correction{{Msg: err.Error()}}, err
 	}
 	zones := *z
 
@@ -617,17 +910,143 @@ func generatePopulateCorrections(provider *models.DNSProviderInstance, zoneName
 	}}, nil
 }
 
-func generateZoneCorrections(zone *models.DomainConfig, provider *models.DNSProviderInstance) ([]*models.Correction, []*models.Correction, int, error) {
-	reports, zoneCorrections, actualChangeCount, err := zonerecs.CorrectZoneRecords(provider.Driver, zone)
+// declaredZones returns the set of zone names (in ASCII form) that are
+// listed in dnsconfig.js.
+func declaredZones(domains []*models.DomainConfig) map[string]bool {
+	declared := make(map[string]bool, len(domains))
+	for _, dc := range domains {
+		aceZoneName, _ := idna.ToASCII(dc.Name)
+		declared[aceZoneName] = true
+	}
+	return declared
+}
+
+// uniqueProviders returns the (selected) DNS providers used by zones,
+// each listed once, in the order they are first seen.
+func uniqueProviders(zones []*models.DomainConfig, filter string) []*models.DNSProviderInstance {
+	var picked []*models.DNSProviderInstance
+	seen := map[string]bool{}
+	for _, zone := range zones {
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, filter) {
+			if !seen[provider.Name] {
+				seen[provider.Name] = true
+				picked = append(picked, provider)
+			}
+		}
+	}
+	return picked
+}
+
+// depopulateZone is a zone that --depopulate deletes from a provider.
+type depopulateZone struct {
+	provider *models.DNSProviderInstance
+	zone     string
+}
+
+// zonesToDepopulate returns the zones that exist at the provider but are not
+// declared in dnsconfig.js. The creds.json entry may restrict which zones are
+// eligible with "_depopulate_allow" and "_depopulate_deny" (comma separated
+// lists of globs).
+func zonesToDepopulate(ctx context.Context, provider *models.DNSProviderInstance, declared map[string]bool, creds map[string]string, zcache *cmdZoneCache) ([]string, error) {
+	lister, ok := provider.Driver.(providers.ZoneLister)
+	if !ok {
+		return nil, nil // We can't generate a list. No corrections are possible.
+	}
+
+	allow, err := zoneglob.CompileList(creds["_depopulate_allow"])
+	if err != nil {
+		return nil, fmt.Errorf("provider %q: invalid _depopulate_allow: %w", provider.Name, err)
+	}
+	deny, err := zoneglob.CompileList(creds["_depopulate_deny"])
+	if err != nil {
+		return nil, fmt.Errorf("provider %q: invalid _depopulate_deny: %w", provider.Name, err)
+	}
+
+	z, err := zcache.zoneList(ctx, provider.Name, lister)
+	if err != nil {
+		return nil, fmt.Errorf("zoneList failed for %q: %w", provider.Name, err)
+	}
+
+	var picked []string
+	for _, zoneName := range *z {
+		if declared[zoneName] {
+			continue // Zone is in dnsconfig.js. Keep it.
+		}
+		if !depopulateAllowed(zoneName, allow, deny) {
+			continue
+		}
+		picked = append(picked, zoneName)
+	}
+	slices.Sort(picked)
+	return picked, nil
+}
+
+// depopulateAllowed returns true if zoneName may be deleted. An empty allow
+// list permits all zones. The deny list takes precedence over the allow list.
+func depopulateAllowed(zoneName string, allow, deny []zoneglob.Matcher) bool {
+	if zoneglob.MatchAny(deny, zoneName) {
+		return false
+	}
+	return len(allow) == 0 || zoneglob.MatchAny(allow, zoneName)
+}
+
+// partialPushConfig returns the rule that leaves the records not selected
+// by --types and --labels as they are (like IGNORE()), or nil if neither
+// is set.
+func partialPushConfig(types, labels string) (*models.UnmanagedConfig, error) {
+	var typeList, labelList []string
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			typeList = append(typeList, t)
+		}
+	}
+	for _, l := range strings.Split(labels, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			if _, err := glob.Compile(l); err != nil {
+				return nil, fmt.Errorf("--labels %q: %w", l, err)
+			}
+			labelList = append(labelList, l)
+		}
+	}
+	if len(typeList) == 0 && len(labelList) == 0 {
+		return nil, nil
+	}
+
+	labelPattern := strings.Join(labelList, ",")
+	if len(labelList) > 1 {
+		labelPattern = "{" + labelPattern + "}"
+	}
+	return &models.UnmanagedConfig{
+		LabelPattern: labelPattern,
+		RTypePattern: strings.Join(typeList, ","),
+		Invert:       true,
+	}, nil
+}
+
+func generateDepopulateCorrections(provider *models.DNSProviderInstance, zoneName string) []*models.Correction {
+	deleter, ok := provider.Driver.(providers.ZoneDeleter)
+	if !ok {
+		return msg(fmt.Sprintf("Zone %q is not in dnsconfig.js. Can not delete because %q does not implement ZoneDeleter", zoneName, provider.Name))
+	}
+
+	return []*models.Correction{{
+		Msg: fmt.Sprintf("Deleting zone %q from %q (not in dnsconfig.js)", zoneName, provider.Name),
+		F:   func() error { return deleter.DeleteZone(zoneName) },
+	}}
+}
+
+func generateZoneCorrections(ctx context.Context, zone *models.DomainConfig, provider *models.DNSProviderInstance, zr *zoneResults) ([]*models.Correction, []*models.Correction, int, error) {
+	result, err := zonerecs.CorrectZoneRecordsContext(ctx, provider.Driver, zone)
 	if err != nil {
 		return []*models.Correction{{Msg: fmt.Sprintf("Domain %q provider %s Error: %s", zone.Name, provider.Name, err)}}, nil, 0, err
 	}
-	return zoneCorrections, reports, actualChangeCount, nil
+	zr.store(zone, provider.Name, result)
+	return result.Corrections, result.Reports, result.ActualChangeCount, nil
 }
 
-func generateDelegationCorrections(zone *models.DomainConfig, providers []*models.DNSProviderInstance, _ *models.RegistrarInstance) ([]*models.Correction, int, error) {
+func generateDelegationCorrections(ctx context.Context, zone *models.DomainConfig, providers []*models.DNSProviderInstance, _ *models.RegistrarInstance) ([]*models.Correction, int, error) {
 	// fmt.Printf("DEBUG: generateDelegationCorrections start zone=%q nsList = %v\n", zone.Name, zone.Nameservers)
-	nsList, err := nameservers.DetermineNameserversForProviders(zone, providers, true)
+	nsList, err := nameservers.DetermineNameserversForProvidersContext(ctx, zone, providers, true)
 	if err != nil {
 		return msg(fmt.Sprintf("DetermineNS: zone %q; Error: %s", zone.Name, err)), 0, err
 	}
@@ -641,7 +1060,7 @@ func generateDelegationCorrections(zone *models.DomainConfig, providers []*model
 		)}}, 0, nil
 	}
 
-	corrections, err := zone.RegistrarInstance.Driver.GetRegistrarCorrections(zone)
+	corrections, err := models.GetRegistrarCorrectionsContext(ctx, zone.RegistrarInstance.Driver, zone)
 	if err != nil {
 		return msg(fmt.Sprintf("zone %q; Rprovider %q; Error: %s", zone.Name, zone.RegistrarInstance.Name, err)), 0, err
 	}
@@ -656,7 +1075,9 @@ func msg(s string) []*models.Correction {
 func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[string]string, notifyFlag bool) (notify notifications.Notifier, err error) {
 	var notificationCfg map[string]string
 	defer func() {
-		notify = notifications.Init(notificationCfg)
+		var nerr error
+		notify, nerr = notifications.Init(notificationCfg)
+		err = cmp.Or(err, nerr)
 	}()
 	if notifyFlag {
 		notificationCfg = providerConfigs["notifications"]
@@ -681,9 +1102,13 @@ func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[
 
 	registrars := map[string]providers.Registrar{}
 	dnsProviders := map[string]providers.DNSServiceProvider{}
+	checked := map[string]bool{}
 	for _, d := range cfg.Domains {
 		if registrars[d.RegistrarName] == nil {
 			rCfg := cfg.RegistrarsByName[d.RegistrarName]
+			if err := validateCreds(d.RegistrarName, rCfg.Type, providerConfigs[d.RegistrarName], checked); err != nil {
+				return nil, err
+			}
 			r, err := providers.CreateRegistrar(rCfg.Type, providerConfigs[d.RegistrarName])
 			if err != nil {
 				return nil, err
@@ -695,6 +1120,9 @@ func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[
 		for _, pInst := range d.DNSProviderInstances {
 			if dnsProviders[pInst.Name] == nil {
 				dCfg := cfg.DNSProvidersByName[pInst.Name]
+				if err := validateCreds(dCfg.Name, dCfg.Type, providerConfigs[dCfg.Name], checked); err != nil {
+					return nil, err
+				}
 				prov, err := providers.CreateDNSProvider(dCfg.Type, providerConfigs[dCfg.Name], dCfg.Metadata)
 				if err != nil {
 					return nil, err
@@ -708,6 +1136,25 @@ func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[
 	return notify, err
 }
 
+// validateCreds checks the creds.json entry name before its provider is
+// created: unknown keys are warnings, missing required keys are errors.
+// Entries in checked are skipped, so that an entry used as both registrar
+// and DNS provider is reported once.
+func validateCreds(name, pType string, config map[string]string, checked map[string]bool) error {
+	if checked[name] {
+		return nil
+	}
+	checked[name] = true
+	unknown, err := providers.ValidateCreds(pType, config)
+	for _, k := range unknown {
+		printer.Warnf("creds.json entry %q: unknown key %q\n", name, k)
+	}
+	if err != nil {
+		return fmt.Errorf("creds.json entry %q: %w", name, err)
+	}
+	return nil
+}
+
 // pproviderTypeFieldName is the name of the field in creds.json that specifies the provider type id.
 const pproviderTypeFieldName = "TYPE"
 
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"flag"
+	"reflect"
 	"testing"
 
 	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zoneglob"
 )
 
 func Test_whichZonesToProcess(t *testing.T) {
@@ -178,3 +181,85 @@ func Test_whichZonesToProcess(t *testing.T) {
 		})
 	}
 }
+
+func Test_depopulateAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		allow string
+		deny  string
+		want  bool
+	}{
+		{"noLists", "example.com", "", "", true},
+		{"allowed", "example.com", "*.com", "", true},
+		{"notAllowed", "example.net", "*.com", "", false},
+		{"denied", "example.com", "", "example.com", false},
+		{"denyWins", "example.com", "*.com", "example.*", false},
+		{"multiAllow", "example.org", "*.com, *.org", "", true},
+		{"globSubdomains", "a.example.com", "*.com", "", true},
+		{"globNotParent", "example.com", "*.example.com", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allow, err := zoneglob.CompileList(tt.allow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deny, err := zoneglob.CompileList(tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := depopulateAllowed(tt.zone, allow, deny); got != tt.want {
+				t.Errorf("depopulateAllowed(%q, %q, %q) = %v, want %v", tt.zone, tt.allow, tt.deny, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_populateFlags(t *testing.T) {
+	for _, tt := range []struct {
+		arg                    string
+		noPopulate, dePopulate bool
+	}{
+		{"--depopulate", false, true},
+		{"--no-populate", true, false},
+	} {
+		var args PPushArgs
+		set := flag.NewFlagSet("push", flag.ContinueOnError)
+		for _, f := range args.flags() {
+			if err := f.Apply(set); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := set.Parse([]string{tt.arg}); err != nil {
+			t.Fatal(err)
+		}
+		if args.NoPopulate != tt.noPopulate || args.DePopulate != tt.dePopulate {
+			t.Errorf("%s: NoPopulate = %v, DePopulate = %v, want %v, %v", tt.arg, args.NoPopulate, args.DePopulate, tt.noPopulate, tt.dePopulate)
+		}
+	}
+}
+
+func Test_partialPushConfig(t *testing.T) {
+	tests := []struct {
+		types, labels string
+		want          *models.UnmanagedConfig
+	}{
+		{"", "", nil},
+		{"txt", "", &models.UnmanagedConfig{RTypePattern: "TXT", Invert: true}},
+		{"TXT, cname", "_acme*", &models.UnmanagedConfig{LabelPattern: "_acme*", RTypePattern: "TXT,CNAME", Invert: true}},
+		{"", "www,@", &models.UnmanagedConfig{LabelPattern: "{www,@}", Invert: true}},
+	}
+	for _, tt := range tests {
+		got, err := partialPushConfig(tt.types, tt.labels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("partialPushConfig(%q, %q) = %+v, want %+v", tt.types, tt.labels, got, tt.want)
+		}
+	}
+	if _, err := partialPushConfig("", "[abc"); err == nil {
+		t.Error("invalid glob: expected an error")
+	}
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"strings"
@@ -22,7 +23,9 @@ type ReportItem struct {
 func InitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[string]string, notifyFlag bool) (notify notifications.Notifier, err error) {
 	var notificationCfg map[string]string
 	defer func() {
-		notify = notifications.Init(notificationCfg)
+		var nerr error
+		notify, nerr = notifications.Init(notificationCfg)
+		err = cmp.Or(err, nerr)
 	}()
 	if notifyFlag {
 		notificationCfg = providerConfigs["notifications"]
@@ -47,9 +50,13 @@ func InitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[s
 
 	registrars := map[string]providers.Registrar{}
 	dnsProviders := map[string]providers.DNSServiceProvider{}
+	checked := map[string]bool{}
 	for _, d := range cfg.Domains {
 		if registrars[d.RegistrarName] == nil {
 			rCfg := cfg.RegistrarsByName[d.RegistrarName]
+			if err := validateCreds(d.RegistrarName, rCfg.Type, providerConfigs[d.RegistrarName], checked); err != nil {
+				return nil, err
+			}
 			r, err := providers.CreateRegistrar(rCfg.Type, providerConfigs[d.RegistrarName])
 			if err != nil {
 				return nil, err
@@ -61,6 +68,9 @@ func InitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[s
 		for _, pInst := range d.DNSProviderInstances {
 			if dnsProviders[pInst.Name] == nil {
 				dCfg := cfg.DNSProvidersByName[pInst.Name]
+				if err := validateCreds(dCfg.Name, dCfg.Type, providerConfigs[dCfg.Name], checked); err != nil {
+					return nil, err
+				}
 				prov, err := providers.CreateDNSProvider(dCfg.Type, providerConfigs[dCfg.Name], dCfg.Metadata)
 				if err != nil {
 					return nil, err
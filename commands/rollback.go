@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
+	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+	"github.com/StackExchange/dnscontrol/v4/providers"
+	"github.com/urfave/cli/v2"
+)
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args RollbackArgs
+	return &cli.Command{
+		Name:  "rollback",
+		Usage: "restore a zone to a snapshot taken by push --journal",
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 1 {
+				return cli.Exit("Arguments should be: snapshot (Ex: journal/20250101T120000.000Z_example.com_bind.json)", 1)
+			}
+			args.Snapshot = ctx.Args().First()
+			return exit(Rollback(args))
+		},
+		Flags:     args.flags(),
+		UsageText: "dnscontrol rollback [command options] snapshot",
+		Description: `Restore a zone to the state recorded in a snapshot.
+
+"push --journal DIR" writes a snapshot of each zone to DIR before changing
+it.  "rollback" reads the snapshot and makes the zone at the same provider
+match it again.  Like "preview", it only lists the changes unless --push
+is given.
+
+EXAMPLES:
+   dnscontrol rollback journal/20250101T120000.000Z_example.com_bind.json
+   dnscontrol rollback --push journal/20250101T120000.000Z_example.com_bind.json`,
+	}
+}())
+
+// RollbackArgs contains all data/flags needed to run rollback, independently of CLI.
+type RollbackArgs struct {
+	GetCredentialsArgs
+	Snapshot    string
+	Push        bool
+	Interactive bool
+}
+
+func (args *RollbackArgs) flags() []cli.Flag {
+	flags := args.GetCredentialsArgs.flags()
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "push",
+		Destination: &args.Push,
+		Usage:       "Perform the changes (the default is to only list them)",
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "i",
+		Destination: &args.Interactive,
+		Usage:       "Interactive. Confirm or Exclude each correction before they run",
+	})
+	return flags
+}
+
+// Rollback implements the rollback subcommand.
+func Rollback(args RollbackArgs) error {
+	out := printer.DefaultPrinter
+
+	entry, err := readJournal(args.Snapshot)
+	if err != nil {
+		return err
+	}
+	out.Printf("Snapshot of %q at %q taken %s by %s\n", entry.Domain, entry.Provider, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.User)
+
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return err
+	}
+	driver, err := providers.CreateDNSProvider(entry.ProviderType, providerConfigs[entry.Provider], entry.ProviderMeta)
+	if err != nil {
+		return err
+	}
+
+	// Desired is the snapshot. Check it as push checks the desired
+	// records, then run it through the normal diff2 path.
+	dc, errs := entry.normalizedDomainConfig()
+	if PrintValidationErrors(errs) {
+		return errors.New("exiting due to validation errors")
+	}
+	result, err := zonerecs.CorrectZoneRecordsDetailed(driver, dc)
+	if err != nil {
+		return fmt.Errorf("domain %q provider %s: %w", dc.Name, entry.Provider, err)
+	}
+
+	out.StartDomain(dc.GetUniqueName())
+	out.StartDNSProvider(entry.Provider, false)
+	out.EndProvider2(entry.Provider, result.ActualChangeCount)
+	corrections := append(result.Reports, result.Corrections...)
+	notifier, err := notifications.Init(nil)
+	if err != nil {
+		return err
+	}
+	ctx, stop := interruptContext(out)
+	defer stop()
+	if pprintOrRunCorrections(ctx, dc.Name, entry.Provider, corrections, out, args.Push, args.Interactive, notifier, "") {
+		return errors.New("completed with errors")
+	}
+	return nil
+}
+
+// normalizedDomainConfig returns the zone of the snapshot after the
+// normalization and validation that push runs, including the record
+// auditor of the snapshot's provider type. A snapshot the provider would
+// now reject is not pushed.
+func (entry *JournalEntry) normalizedDomainConfig() (*models.DomainConfig, []error) {
+	dc := entry.domainConfig()
+	dc.DNSProviderInstances = []*models.DNSProviderInstance{{
+		ProviderBase: models.ProviderBase{Name: entry.Provider, ProviderType: entry.ProviderType},
+	}}
+	cfg := &models.DNSConfig{Domains: []*models.DomainConfig{dc}}
+	return dc, normalize.ValidateAndNormalizeConfig(cfg)
+}
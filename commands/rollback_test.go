@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+func Test_normalizedDomainConfig(t *testing.T) {
+	entry := &JournalEntry{
+		Domain:       "example.com",
+		Provider:     "bind",
+		ProviderType: "BIND",
+		Existing:     models.Records{testRecord("www", "A", "1.2.3.4", 0)},
+	}
+	dc, errs := entry.normalizedDomainConfig()
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if got := dc.Records[0].TTL; got != models.DefaultTTL {
+		t.Errorf("TTL not normalized: got %d", got)
+	}
+
+	// The provider's auditor runs on the snapshot.
+	entry = &JournalEntry{
+		Domain:       "example.com",
+		Provider:     "do",
+		ProviderType: "DIGITALOCEAN",
+		Existing:     models.Records{testRecord("txt", "TXT", `back\slash`, 300)},
+	}
+	if _, errs := entry.normalizedDomainConfig(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "DIGITALOCEAN rejects domain example.com") {
+		t.Errorf("auditor: got %v", errs)
+	}
+}
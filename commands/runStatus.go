@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+// credsTimeoutKey is the creds.json key that overrides --timeout for a
+// provider.  For example: "_timeout": "2m"
+const credsTimeoutKey = "_timeout"
+
+// providerTimeouts is the time limit of each provider's work on one zone:
+// gathering the zone's data, and running its corrections.
+type providerTimeouts struct {
+	global     time.Duration
+	byProvider map[string]time.Duration
+}
+
+// newProviderTimeouts returns the timeouts set by --timeout and by the
+// "_timeout" setting of each provider in creds.json.
+func newProviderTimeouts(global time.Duration, providerConfigs map[string]map[string]string) (providerTimeouts, error) {
+	t := providerTimeouts{global: global, byProvider: map[string]time.Duration{}}
+	for name, vals := range providerConfigs {
+		v, ok := vals[credsTimeoutKey]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return t, fmt.Errorf("creds.json: provider %q: invalid %s %q", name, credsTimeoutKey, v)
+		}
+		t.byProvider[name] = d
+	}
+	return t, nil
+}
+
+func (t providerTimeouts) get(providerName string) time.Duration {
+	if d, ok := t.byProvider[providerName]; ok {
+		return d
+	}
+	return t.global
+}
+
+// context returns a context for a provider's work. It is cancelled when
+// parent is, or when the provider's timeout expires.
+func (t providerTimeouts) context(parent context.Context, providerName string) (context.Context, context.CancelFunc) {
+	if d := t.get(providerName); d > 0 {
+		return context.WithTimeout(parent, d)
+	}
+	return context.WithCancel(parent)
+}
+
+// Zone states, in increasing order of severity.
+const (
+	zoneFinished = iota
+	zoneFailed
+	zoneCancelled
+)
+
+var zoneStateNames = []string{"finished", "failed", "cancelled"}
+
+type zoneStatusItem struct {
+	state int
+	msg   string
+}
+
+// runStatus applies the timeouts and records the outcome of each
+// zone/provider pair so that a summary can be printed at the end of the run
+// (especially when it is interrupted). It is safe for concurrent use.
+//
+// A provider call that times out can not be stopped: it keeps running in
+// the background (see models.CallContext). The provider is then marked as
+// abandoned, and its remaining zones are skipped, since the provider may
+// not be safe for concurrent use.
+type runStatus struct {
+	timeouts providerTimeouts
+
+	sync.Mutex
+	m         map[[2]string]*zoneStatusItem
+	abandoned map[string]bool // Providers with a call that may still be running.
+}
+
+func newRunStatus(timeouts providerTimeouts) *runStatus {
+	return &runStatus{timeouts: timeouts, m: map[[2]string]*zoneStatusItem{}, abandoned: map[string]bool{}}
+}
+
+// abandonKey is the context key of the function that marks the provider
+// of a context as abandoned.
+type abandonKey struct{}
+
+// context returns the context for a provider's work on a zone.
+func (rs *runStatus) context(parent context.Context, providerName string) (context.Context, context.CancelFunc) {
+	ctx, cancel := rs.timeouts.context(parent, providerName)
+	return context.WithValue(ctx, abandonKey{}, func() { rs.abandon(providerName) }), cancel
+}
+
+// noteAbandoned marks the provider of ctx (see runStatus.context) as
+// abandoned if err says that a call was abandoned while still running.
+func noteAbandoned(ctx context.Context, err error) {
+	if !errors.Is(err, models.ErrAbandoned) {
+		return
+	}
+	if abandon, ok := ctx.Value(abandonKey{}).(func()); ok {
+		abandon()
+	}
+}
+
+func (rs *runStatus) abandon(providerName string) {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.abandoned[providerName] = true
+}
+
+// skip returns an error if providerName must not be used any more because
+// a call to it was abandoned while still running.
+func (rs *runStatus) skip(providerName string) error {
+	rs.Lock()
+	defer rs.Unlock()
+	if rs.abandoned[providerName] {
+		return fmt.Errorf("skipped: an earlier call to %s timed out and may still be running", providerName)
+	}
+	return nil
+}
+
+// record records the result of some work on a zone at a provider. A zone
+// keeps its most severe state.
+func (rs *runStatus) record(zoneName, providerName string, err error) {
+	if errors.Is(err, models.ErrAbandoned) {
+		rs.abandon(providerName)
+	}
+	state, msg := zoneFinished, ""
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		state = zoneCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		state, msg = zoneFailed, fmt.Sprintf("timed out after %s", rs.timeouts.get(providerName))
+	default:
+		state, msg = zoneFailed, err.Error()
+	}
+
+	rs.Lock()
+	defer rs.Unlock()
+	k := [2]string{zoneName, providerName}
+	if cur, ok := rs.m[k]; !ok || state > cur.state {
+		rs.m[k] = &zoneStatusItem{state: state, msg: msg}
+	}
+}
+
+// recordCorrections records the result of pprintOrRunCorrections. ctx is
+// the context the corrections ran with.
+func (rs *runStatus) recordCorrections(ctx context.Context, zoneName, providerName string, failed bool) {
+	switch {
+	case !failed:
+		rs.record(zoneName, providerName, nil)
+	case ctx.Err() != nil:
+		rs.record(zoneName, providerName, ctx.Err())
+	default:
+		rs.record(zoneName, providerName, errors.New("corrections failed"))
+	}
+}
+
+// print prints the number of zones in each state and lists the zones that
+// did not finish. If everything finished, nothing is printed unless full
+// is set.
+func (rs *runStatus) print(out printer.CLI, full bool) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	var counts [3]int
+	var keys [][2]string
+	for k, item := range rs.m {
+		counts[item.state]++
+		if item.state != zoneFinished {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 && !full {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	out.Printf("Summary: %d finished, %d failed, %d cancelled\n", counts[zoneFinished], counts[zoneFailed], counts[zoneCancelled])
+	for _, k := range keys {
+		item := rs.m[k]
+		line := fmt.Sprintf("  %-9s %s (%s)", zoneStateNames[item.state], k[0], k[1])
+		if item.msg != "" {
+			line += ": " + item.msg
+		}
+		out.Printf("%s\n", line)
+	}
+}
+
+// interruptContext returns a context that is cancelled when dnscontrol is
+// interrupted (Ctrl-C or SIGTERM).  A second interrupt kills dnscontrol
+// immediately, as usual.  stop must be called when the context is no
+// longer needed.
+func interruptContext(out printer.CLI) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-ch:
+			signal.Stop(ch)
+			out.Warnf("Interrupted. Waiting for the running corrections to finish. Interrupt again to quit immediately.\n")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel()
+	}
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+func Test_newProviderTimeouts(t *testing.T) {
+	timeouts, err := newProviderTimeouts(time.Minute, map[string]map[string]string{
+		"slow": {"TYPE": "ROUTE53", "_timeout": "5m"},
+		"fast": {"TYPE": "BIND"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := timeouts.get("slow"); got != 5*time.Minute {
+		t.Errorf("slow: got %s", got)
+	}
+	if got := timeouts.get("fast"); got != time.Minute {
+		t.Errorf("fast: got %s", got)
+	}
+
+	if _, err := newProviderTimeouts(0, map[string]map[string]string{"bad": {"_timeout": "soon"}}); err == nil {
+		t.Errorf("expected an error for an invalid _timeout")
+	}
+}
+
+func Test_runStatus(t *testing.T) {
+	status := newRunStatus(providerTimeouts{global: 30 * time.Second})
+	status.record("a.com", "p1", nil)
+	status.record("b.com", "p1", nil)
+	status.record("b.com", "p1", context.DeadlineExceeded)
+	status.record("b.com", "p1", nil) // Does not hide the failure.
+	status.record("c.com", "p1", errors.New("boom"))
+	status.record("c.com", "p1", context.Canceled)
+	status.record("d.com", "p2", context.Canceled)
+
+	var buf bytes.Buffer
+	status.print(&printer.ConsolePrinter{Writer: &buf}, false)
+	want := `Summary: 1 finished, 1 failed, 2 cancelled
+  failed    b.com (p1): timed out after 30s
+  cancelled c.com (p1)
+  cancelled d.com (p2)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Nothing is printed when everything finished.
+	buf.Reset()
+	status = newRunStatus(providerTimeouts{})
+	status.record("a.com", "p1", nil)
+	status.print(&printer.ConsolePrinter{Writer: &buf}, false)
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+func Test_runStatusAbandoned(t *testing.T) {
+	status := newRunStatus(providerTimeouts{byProvider: map[string]time.Duration{"slow": 10 * time.Millisecond}})
+	release := make(chan struct{})
+	defer close(release)
+	hung := &models.Correction{Msg: "hung", F: func() error { <-release; return nil }}
+
+	ctx, cancel := status.context(context.Background(), "slow")
+	err := runCorrection(ctx, hung)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, models.ErrAbandoned) {
+		t.Fatalf("runCorrection: got %v", err)
+	}
+	if status.skip("slow") == nil {
+		t.Error("the provider of an abandoned correction is not skipped")
+	}
+	if err := status.skip("fast"); err != nil {
+		t.Errorf("other provider: %v", err)
+	}
+
+	// An abandoned call while gathering data is recorded the same way.
+	status = newRunStatus(providerTimeouts{})
+	status.record("a.com", "p1", context.DeadlineExceeded)
+	if err := status.skip("p1"); err != nil {
+		t.Errorf("a timeout that was not abandoned: %v", err)
+	}
+	status.record("a.com", "p1", fmt.Errorf("%w (%w)", context.DeadlineExceeded, models.ErrAbandoned))
+	if status.skip("p1") == nil {
+		t.Error("abandoned call not recorded")
+	}
+}
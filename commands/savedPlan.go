@@ -0,0 +1,276 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/version"
+)
+
+// A saved plan lets "push --plan" apply exactly what "preview --out-plan"
+// showed.  The plan contains the desired configuration (the IR, before
+// normalization), a fingerprint of the records that existed at each
+// zone/provider when the plan was made, a fingerprint of the changes each
+// registrar would make to the delegation of each zone, and the zones that
+// --depopulate would delete.  Push refuses to run if any of them no longer
+// matches (i.e. the zone has drifted).
+
+// savedPlanVersion is incremented when the file format changes.
+const savedPlanVersion = 2
+
+// SavedPlan is the content of a --out-plan file.
+type SavedPlan struct {
+	Version    int              `json:"version"`
+	Created    time.Time        `json:"created"`
+	DNSControl string           `json:"dnscontrol"`
+	Config     json.RawMessage  `json:"config"` // The desired IR.
+	Zones      []*SavedPlanZone `json:"zones"`
+}
+
+// SavedPlanZone records the existing state of a zone at a provider.
+type SavedPlanZone struct {
+	Domain      string `json:"domain"` // The zone's unique name (example.com!tag).
+	Provider    string `json:"provider"`
+	Populate    bool   `json:"populate,omitempty"`   // The zone does not exist yet and will be created.
+	Depopulate  bool   `json:"depopulate,omitempty"` // The zone is not in the config and will be deleted (--depopulate).
+	Registrar   bool   `json:"registrar,omitempty"`  // Provider is the zone's registrar. The fingerprint is of its corrections.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Records     int    `json:"records"`
+}
+
+// newSavedPlan starts a plan for the configuration cfg.  It must be called
+// before cfg is normalized.
+func newSavedPlan(cfg *models.DNSConfig) (*SavedPlan, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedPlan{
+		Version:    savedPlanVersion,
+		Created:    time.Now().UTC(),
+		DNSControl: version.Version(),
+		Config:     b,
+	}, nil
+}
+
+// readSavedPlan reads a plan file and returns the plan and its configuration.
+func readSavedPlan(fname string) (*SavedPlan, *models.DNSConfig, error) {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan := &SavedPlan{}
+	if err := json.Unmarshal(b, plan); err != nil {
+		return nil, nil, fmt.Errorf("parsing plan %q: %w", fname, err)
+	}
+	if plan.Version != savedPlanVersion {
+		return nil, nil, fmt.Errorf("plan %q has version %d, this version of dnscontrol reads version %d", fname, plan.Version, savedPlanVersion)
+	}
+
+	cfg := &models.DNSConfig{}
+	if err := json.Unmarshal(plan.Config, cfg); err != nil {
+		return nil, nil, fmt.Errorf("parsing plan %q config: %w", fname, err)
+	}
+	cfg, err = preloadProviders(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plan, cfg, nil
+}
+
+// zone returns the plan's record of a zone at a provider, creating it
+// if needed.
+func (p *SavedPlan) zone(zone *models.DomainConfig, providerName string) *SavedPlanZone {
+	if z := p.lookup(zone, providerName); z != nil {
+		return z
+	}
+	z := &SavedPlanZone{Domain: zone.GetUniqueName(), Provider: providerName}
+	p.Zones = append(p.Zones, z)
+	return z
+}
+
+// lookup is like zone but does not create missing entries.
+func (p *SavedPlan) lookup(zone *models.DomainConfig, providerName string) *SavedPlanZone {
+	for _, z := range p.Zones {
+		if z.Domain == zone.GetUniqueName() && z.Provider == providerName && !z.Registrar && !z.Depopulate {
+			return z
+		}
+	}
+	return nil
+}
+
+// lookupRegistrar returns the plan's record of the delegation of a zone.
+func (p *SavedPlan) lookupRegistrar(zone *models.DomainConfig) *SavedPlanZone {
+	for _, z := range p.Zones {
+		if z.Domain == zone.GetUniqueName() && z.Provider == zone.RegistrarInstance.Name && z.Registrar {
+			return z
+		}
+	}
+	return nil
+}
+
+// recordPopulate records which zones need to be created at each provider.
+func (p *SavedPlan) recordPopulate(zones []*models.DomainConfig, filter string) {
+	for _, zone := range zones {
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, filter) {
+			p.zone(zone, provider.Name).Populate = countActions(zone.GetPopulateCorrections(provider.Name)) != 0
+		}
+	}
+}
+
+// recordExisting records the fingerprint of the existing records of each
+// zone at each provider, and of the corrections of each zone's registrar.
+func (p *SavedPlan) recordExisting(zones []*models.DomainConfig, filter string, zr *zoneResults) {
+	for _, zone := range zones {
+		if c, ok := zr.delegation(zone); ok {
+			p.Zones = append(p.Zones, &SavedPlanZone{
+				Domain:      zone.GetUniqueName(),
+				Provider:    zone.RegistrarInstance.Name,
+				Registrar:   true,
+				Fingerprint: fingerprintCorrections(c),
+			})
+		}
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, filter) {
+			z := p.zone(zone, provider.Name)
+			if r := zr.get(zone, provider.Name); r != nil {
+				z.Fingerprint = fingerprintRecords(r.Existing)
+				z.Records = len(r.Existing)
+			}
+		}
+	}
+}
+
+// checkPopulate returns an error if the zones that need to be created
+// differ from when the plan was made.
+func (p *SavedPlan) checkPopulate(zones []*models.DomainConfig, filter string) error {
+	var drifted []string
+	for _, zone := range zones {
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, filter) {
+			z := p.lookup(zone, provider.Name)
+			if z == nil {
+				drifted = append(drifted, fmt.Sprintf("%s (%s): not in plan", zone.GetUniqueName(), provider.Name))
+				continue
+			}
+			populate := countActions(zone.GetPopulateCorrections(provider.Name)) != 0
+			if populate != z.Populate {
+				drifted = append(drifted, fmt.Sprintf("%s (%s): zone existence changed", zone.GetUniqueName(), provider.Name))
+			}
+		}
+	}
+	return driftError(drifted)
+}
+
+// checkExisting returns an error if the existing records of any zone, or
+// the changes its registrar would make, differ from when the plan was made.
+// Zones that the plan created are not checked since their initial contents
+// are provider-specific.
+func (p *SavedPlan) checkExisting(zones []*models.DomainConfig, filter string, zr *zoneResults) error {
+	var drifted []string
+	for _, zone := range zones {
+		if c, ok := zr.delegation(zone); ok {
+			z := p.lookupRegistrar(zone)
+			if z == nil {
+				drifted = append(drifted, fmt.Sprintf("%s (%s): registrar not in plan", zone.GetUniqueName(), zone.RegistrarInstance.Name))
+			} else if fingerprintCorrections(c) != z.Fingerprint {
+				drifted = append(drifted, fmt.Sprintf("%s (%s): the delegation changed", zone.GetUniqueName(), zone.RegistrarInstance.Name))
+			}
+		}
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, filter) {
+			z := p.lookup(zone, provider.Name)
+			if z == nil {
+				drifted = append(drifted, fmt.Sprintf("%s (%s): not in plan", zone.GetUniqueName(), provider.Name))
+				continue
+			}
+			if z.Populate {
+				continue
+			}
+			r := zr.get(zone, provider.Name)
+			if r == nil {
+				continue // Gathering failed. The error is reported elsewhere.
+			}
+			if fp := fingerprintRecords(r.Existing); fp != z.Fingerprint {
+				drifted = append(drifted, fmt.Sprintf("%s (%s): %d records in plan, %d now", zone.GetUniqueName(), provider.Name, z.Records, len(r.Existing)))
+			}
+		}
+	}
+	return driftError(drifted)
+}
+
+// recordDepopulate records the zones that --depopulate will delete.
+func (p *SavedPlan) recordDepopulate(deletions []depopulateZone) {
+	for _, d := range deletions {
+		p.Zones = append(p.Zones, &SavedPlanZone{Domain: d.zone, Provider: d.provider.Name, Depopulate: true})
+	}
+}
+
+// checkDepopulate returns an error if the zones that --depopulate would
+// delete differ from the ones in the plan.
+func (p *SavedPlan) checkDepopulate(deletions []depopulateZone) error {
+	planned := map[string]bool{}
+	for _, z := range p.Zones {
+		if z.Depopulate {
+			planned[z.Domain+" ("+z.Provider+")"] = true
+		}
+	}
+	var drifted []string
+	for _, d := range deletions {
+		key := d.zone + " (" + d.provider.Name + ")"
+		if !planned[key] {
+			drifted = append(drifted, key+": would be deleted, not in plan")
+		}
+		delete(planned, key)
+	}
+	for _, key := range slices.Sorted(maps.Keys(planned)) {
+		drifted = append(drifted, key+": deletion in plan, no longer needed")
+	}
+	return driftError(drifted)
+}
+
+func driftError(drifted []string) error {
+	if len(drifted) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the live state no longer matches the plan. Run preview again.\n  %s", strings.Join(drifted, "\n  "))
+}
+
+// write writes the plan to fname.
+func (p *SavedPlan) write(fname string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, append(b, '\n'), 0o644)
+}
+
+// fingerprintRecords returns a hash of the records that does not depend on
+// their order.
+func fingerprintRecords(recs models.Records) string {
+	lines := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		lines = append(lines, fmt.Sprintf("%s %d %s %s", rec.NameFQDN, rec.TTL, rec.Type, rec.ToComparableNoTTL()))
+	}
+	sort.Strings(lines)
+	h := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// fingerprintCorrections returns a hash of the actions among corrections.
+func fingerprintCorrections(corrections []*models.Correction) string {
+	var lines []string
+	for _, c := range corrections {
+		if c.F != nil {
+			lines = append(lines, c.Msg)
+		}
+	}
+	h := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(h[:])
+}
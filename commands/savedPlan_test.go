@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_fingerprintRecords(t *testing.T) {
+	a := testRecord("@", "A", "1.2.3.4", 300)
+	b := testRecord("www", "CNAME", "example.com.", 300)
+	bTTL := testRecord("www", "CNAME", "example.com.", 600)
+	c := testRecord("www", "CNAME", "example.net.", 300)
+
+	base := fingerprintRecords(models.Records{a, b})
+	if got := fingerprintRecords(models.Records{b, a}); got != base {
+		t.Errorf("fingerprint depends on order")
+	}
+	if got := fingerprintRecords(models.Records{a, bTTL}); got == base {
+		t.Errorf("fingerprint ignores TTL")
+	}
+	if got := fingerprintRecords(models.Records{a, c}); got == base {
+		t.Errorf("fingerprint ignores target")
+	}
+	if got := fingerprintRecords(models.Records{a}); got == base {
+		t.Errorf("fingerprint ignores missing records")
+	}
+}
+
+func Test_savedPlanDepopulate(t *testing.T) {
+	bind := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "bind"}}
+	plan := &SavedPlan{}
+	plan.recordDepopulate([]depopulateZone{{bind, "old.example.com"}})
+
+	if err := plan.checkDepopulate([]depopulateZone{{bind, "old.example.com"}}); err != nil {
+		t.Errorf("same deletions: %v", err)
+	}
+	if err := plan.checkDepopulate([]depopulateZone{{bind, "old.example.com"}, {bind, "new.example.com"}}); err == nil || !strings.Contains(err.Error(), "new.example.com (bind): would be deleted") {
+		t.Errorf("extra deletion: got %v", err)
+	}
+	if err := plan.checkDepopulate(nil); err == nil || !strings.Contains(err.Error(), "old.example.com (bind): deletion in plan") {
+		t.Errorf("missing deletion: got %v", err)
+	}
+	if err := (&SavedPlan{}).checkDepopulate([]depopulateZone{{bind, "old.example.com"}}); err == nil {
+		t.Error("deletion not in plan was accepted")
+	}
+}
+
+func Test_savedPlanRoundTrip(t *testing.T) {
+	cfg := &models.DNSConfig{
+		Registrars:   []*models.RegistrarConfig{{Name: "reg", Type: "NONE"}},
+		DNSProviders: []*models.DNSProviderConfig{{Name: "bind", Type: "BIND"}},
+		Domains: []*models.DomainConfig{{
+			Name:             "example.com",
+			RegistrarName:    "reg",
+			DNSProviderNames: map[string]int{"bind": -1},
+			Records:          models.Records{testRecord("www", "A", "1.2.3.4", 300)},
+		}},
+	}
+	cfg.Domains[0].UpdateSplitHorizonNames()
+	plan, err := newSavedPlan(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = preloadProviders(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// The state of the zone when the plan is made.
+	existing := models.Records{testRecord("@", "A", "1.2.3.4", 300)}
+	delegation := []*models.Correction{{Msg: "Update nameservers to ns1.example.net", F: func() error { return nil }}}
+	zr := &zoneResults{}
+	zr.store(cfg.Domains[0], "bind", &zonerecs.Result{Existing: existing})
+	zr.storeDelegation(cfg.Domains[0], delegation)
+	plan.recordExisting(cfg.Domains, "all", zr)
+
+	fname := filepath.Join(t.TempDir(), "plan.json")
+	if err := plan.write(fname); err != nil {
+		t.Fatal(err)
+	}
+	got, gotCfg, err := readSavedPlan(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotCfg.Domains) != 1 || gotCfg.Domains[0].Name != "example.com" || len(gotCfg.Domains[0].Records) != 1 {
+		t.Fatalf("config: got %+v", gotCfg.Domains)
+	}
+	zone := gotCfg.Domains[0]
+	if zone.RegistrarInstance.Name != "reg" || len(zone.DNSProviderInstances) != 1 || zone.DNSProviderInstances[0].Name != "bind" {
+		t.Fatalf("providers: got %+v %+v", zone.RegistrarInstance, zone.DNSProviderInstances)
+	}
+
+	state := func(existing models.Records, delegation []*models.Correction) *zoneResults {
+		zr := &zoneResults{}
+		zr.store(zone, "bind", &zonerecs.Result{Existing: existing})
+		zr.storeDelegation(zone, delegation)
+		return zr
+	}
+	if err := got.checkExisting(gotCfg.Domains, "all", state(existing, delegation)); err != nil {
+		t.Errorf("no drift: %v", err)
+	}
+
+	// push --plan refuses to run if a zone, or its delegation, drifted.
+	drifted := append(slices.Clone(existing), testRecord("new", "A", "1.2.3.5", 300))
+	if err := got.checkExisting(gotCfg.Domains, "all", state(drifted, delegation)); err == nil || !strings.Contains(err.Error(), "example.com (bind): 1 records in plan, 2 now") {
+		t.Errorf("records drifted: got %v", err)
+	}
+	redelegated := []*models.Correction{{Msg: "Update nameservers to ns2.example.net", F: func() error { return nil }}}
+	if err := got.checkExisting(gotCfg.Domains, "all", state(existing, redelegated)); err == nil || !strings.Contains(err.Error(), "example.com (reg): the delegation changed") {
+		t.Errorf("delegation drifted: got %v", err)
+	}
+	// Informational messages are not part of the fingerprint.
+	noted := append(slices.Clone(delegation), &models.Correction{Msg: "note"})
+	if err := got.checkExisting(gotCfg.Domains, "all", state(existing, noted)); err != nil {
+		t.Errorf("informational message: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zoneglob"
+	"github.com/gobwas/glob"
+)
+
+// A selector picks zones (--domains, --select) or providers (--providers).
+// It is a comma-separated list of terms (commas inside braces, as in
+// "{a,b}.com" or "re:^a{1,3}$", do not separate terms):
+//
+//	all            everything
+//	example.com    a name (zones: with the usual !tag rules)
+//	*.example.*    a glob; "*" also matches dots
+//	re:^corp-      a regular expression, which must match the whole name
+//	owner=payments a metadata value (a glob, or re:): the D() metadata of
+//	               a zone, or the "type" of a provider
+//	-term          excludes what term matches
+//	@file          the terms listed in file, one or more per line; "#"
+//	               starts a comment
+//
+// An item is picked if it matches any term and no excluding term. If there
+// are only excluding terms, they apply to the default list: all zones, or
+// the default providers.
+
+// itemMatcher reports whether a term matches an item.
+type itemMatcher func(name string, meta map[string]string) bool
+
+// selector is a parsed selector.
+type selector struct {
+	all     bool
+	include []itemMatcher
+	exclude []itemMatcher
+}
+
+// selectorTerms splits a selector into terms, replacing @file with the
+// terms listed in the file.
+func selectorTerms(filter string) ([]string, error) {
+	var terms []string
+	for _, term := range zoneglob.SplitList(filter) {
+		if !strings.HasPrefix(term, "@") && !strings.HasPrefix(term, "-@") {
+			terms = append(terms, term)
+			continue
+		}
+		fname, neg := strings.CutPrefix(term, "-")
+		listed, err := readSelectorFile(strings.TrimPrefix(fname, "@"))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range listed {
+			if neg && !strings.HasPrefix(t, "-") {
+				t = "-" + t
+			}
+			terms = append(terms, t)
+		}
+	}
+	return terms, nil
+}
+
+// readSelectorFile returns the terms listed in fname.
+func readSelectorFile(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("selector: %w", err)
+	}
+	defer f.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, term := range zoneglob.SplitList(strings.Join(strings.Fields(line), ",")) {
+			if strings.HasPrefix(strings.TrimPrefix(term, "-"), "@") {
+				return nil, fmt.Errorf("selector: %s: files can not include other files (%s)", fname, term)
+			}
+			terms = append(terms, term)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("selector: %s: %w", fname, err)
+	}
+	return terms, nil
+}
+
+// newSelector parses filter. namePattern compiles the terms that match
+// names.
+func newSelector(filter string, namePattern func(string) (func(string) bool, error)) (*selector, error) {
+	terms, err := selectorTerms(filter)
+	if err != nil {
+		return nil, err
+	}
+	s := &selector{}
+	for _, term := range terms {
+		if term == "all" {
+			s.all = true
+			continue
+		}
+		neg := strings.HasPrefix(term, "-")
+		m, err := compileTerm(strings.TrimPrefix(term, "-"), namePattern)
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", term, err)
+		}
+		if neg {
+			s.exclude = append(s.exclude, m)
+		} else {
+			s.include = append(s.include, m)
+		}
+	}
+	return s, nil
+}
+
+// compileTerm compiles a term without its leading "-".
+func compileTerm(term string, namePattern func(string) (func(string) bool, error)) (itemMatcher, error) {
+	if key, pattern, ok := strings.Cut(term, "="); ok && !strings.HasPrefix(term, "re:") {
+		match, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(_ string, meta map[string]string) bool { return match(meta[key]) }, nil
+	}
+	match, err := namePattern(term)
+	if err != nil {
+		return nil, err
+	}
+	return func(name string, _ map[string]string) bool { return match(name) }, nil
+}
+
+// compilePattern compiles a glob, or a regular expression that starts
+// with "re:". Both must match the whole string.
+func compilePattern(pattern string) (func(string) bool, error) {
+	if re, ok := strings.CutPrefix(pattern, "re:"); ok {
+		r, err := regexp.Compile(`^(?:` + re + `)$`)
+		if err != nil {
+			return nil, err
+		}
+		return r.MatchString, nil
+	}
+	g, err := glob.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return g.Match, nil
+}
+
+// domainPattern compiles a term that matches the unique name of a zone.
+// A regular expression matches the whole unique name ("example.com!tag").
+// Otherwise the !tag rules of domainInList apply.
+func domainPattern(pattern string) (func(string) bool, error) {
+	if strings.HasPrefix(pattern, "re:") {
+		return compilePattern(pattern)
+	}
+	for _, p := range strings.SplitN(pattern, "!", 2) {
+		if _, err := zoneglob.Compile(p); err != nil {
+			return nil, err
+		}
+	}
+	return func(name string) bool { return domainInList(name, []string{pattern}) }, nil
+}
+
+// match reports whether the item is picked. isDefault is used when the
+// selector has no including term.
+func (s *selector) match(name string, meta map[string]string, isDefault bool) bool {
+	for _, m := range s.exclude {
+		if m(name, meta) {
+			return false
+		}
+	}
+	if s.all {
+		return true
+	}
+	if len(s.include) == 0 {
+		return isDefault
+	}
+	for _, m := range s.include {
+		if m(name, meta) {
+			return true
+		}
+	}
+	return false
+}
+
+// providerMeta is the metadata that a provider selector can match.
+func providerMeta(p *models.DNSProviderInstance) map[string]string {
+	return map[string]string{"type": p.ProviderType}
+}
+
+// prepare reads the files named in the selectors and checks the selectors,
+// so that errors are reported before any work is done.
+func (args *FilterArgs) prepare() error {
+	for _, f := range []struct {
+		flag        string
+		value       *string
+		namePattern func(string) (func(string) bool, error)
+	}{
+		{"domains", &args.Domains, domainPattern},
+		{"providers", &args.Providers, compilePattern},
+		{"select", &args.Select, func(p string) (func(string) bool, error) {
+			return nil, fmt.Errorf("expected key=value")
+		}},
+	} {
+		terms, err := selectorTerms(*f.value)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", f.flag, err)
+		}
+		*f.value = strings.Join(terms, ",")
+		if _, err := newSelector(*f.value, f.namePattern); err != nil {
+			return fmt.Errorf("--%s: %w", f.flag, err)
+		}
+	}
+	return nil
+}
+
+// whichZones returns the zones picked by --domains and --select.
+func (args *FilterArgs) whichZones(domains []*models.DomainConfig) []*models.DomainConfig {
+	return whichZonesToProcess(whichZonesToProcess(domains, args.Domains), args.Select)
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+func Test_whichZonesToProcess_selectors(t *testing.T) {
+	var all []*models.DomainConfig
+	for _, d := range []struct{ name, owner string }{
+		{"example.com", "payments"},
+		{"www.example.net", "web"},
+		{"legacy.com", "payments"},
+		{"corp-a.org", ""},
+		{"example.com!inside", "it"},
+	} {
+		dc := &models.DomainConfig{Name: d.name, Metadata: map[string]string{}}
+		if d.owner != "" {
+			dc.Metadata["owner"] = d.owner
+		}
+		dc.UpdateSplitHorizonNames()
+		all = append(all, dc)
+	}
+
+	listFile := filepath.Join(t.TempDir(), "zones.txt")
+	os.WriteFile(listFile, []byte("# Zones owned by web.\nwww.example.net\n\ncorp-a.org, legacy.com # and these\n"), 0o600)
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"*.example.*", []string{"www.example.net"}},
+		{"example.*", []string{"example.com", "example.com!inside"}},
+		{"example.*!", []string{"example.com"}},
+		{"example.*!*", []string{"example.com!inside"}},
+		{"all,-legacy.com", []string{"example.com", "www.example.net", "corp-a.org", "example.com!inside"}},
+		{"-legacy.com,-*.net", []string{"example.com", "corp-a.org", "example.com!inside"}},
+		{"*.com,-legacy.com", []string{"example.com", "example.com!inside"}},
+		{"re:corp-.*", []string{"corp-a.org"}},
+		{"re:example\\.com(!.*)?", []string{"example.com", "example.com!inside"}},
+		{"owner=payments", []string{"example.com", "legacy.com"}},
+		{"owner=pay*,-legacy.*", []string{"example.com"}},
+		{"owner=re:web|it", []string{"www.example.net", "example.com!inside"}},
+		{"-owner=payments", []string{"www.example.net", "corp-a.org", "example.com!inside"}},
+		{"@" + listFile, []string{"www.example.net", "legacy.com", "corp-a.org"}},
+		{"all,-@" + listFile, []string{"example.com", "example.com!inside"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			var got []string
+			for _, dc := range whichZonesToProcess(all, tt.filter) {
+				got = append(got, dc.GetUniqueName())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_whichProvidersToProcess(t *testing.T) {
+	mk := func(name, pType string, isDefault bool) *models.DNSProviderInstance {
+		return &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: name, ProviderType: pType, IsDefault: isDefault}}
+	}
+	all := []*models.DNSProviderInstance{
+		mk("r53_main", "ROUTE53", true),
+		mk("r53_test", "ROUTE53", false),
+		mk("cloudflare", "CLOUDFLAREAPI", true),
+		mk("bind", "BIND", true),
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"r53_main", "cloudflare", "bind"}},
+		{"all", []string{"r53_main", "r53_test", "cloudflare", "bind"}},
+		{"r53_test", []string{"r53_test"}},
+		{"r53_*", []string{"r53_main", "r53_test"}},
+		{"-bind", []string{"r53_main", "cloudflare"}},
+		{"all,-bind", []string{"r53_main", "r53_test", "cloudflare"}},
+		{"type=ROUTE53,-*_test", []string{"r53_main"}},
+		{"re:r53_.*|bind", []string{"r53_main", "r53_test", "bind"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			var got []string
+			for _, p := range whichProvidersToProcess(all, tt.filter) {
+				got = append(got, p.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterArgs_prepare(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "zones.txt")
+	os.WriteFile(listFile, []byte("a.com\nb.com # comment\n"), 0o600)
+
+	args := FilterArgs{Domains: "@" + listFile + ",-c.com", Providers: "r53_*", Select: "owner=payments"}
+	if err := args.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if args.Domains != "a.com,b.com,-c.com" {
+		t.Errorf("Domains: got %q", args.Domains)
+	}
+
+	// Commas inside braces do not separate terms.
+	for filter, want := range map[string][]string{
+		"{a,b}.example.com":     {"a.example.com", "b.example.com"},
+		`re:^a{1,3}\.com$`:      {"a.com", "aaa.com"},
+		"{a,b}.example.com,-b*": {"a.example.com"},
+	} {
+		sel, err := newSelector(filter, domainPattern)
+		if err != nil {
+			t.Errorf("%s: %v", filter, err)
+			continue
+		}
+		var got []string
+		for _, name := range []string{"a.com", "aaa.com", "aaaa.com", "a.example.com", "b.example.com", "c.example.com"} {
+			if sel.match(name, nil, false) {
+				got = append(got, name)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", filter, got, want)
+		}
+	}
+
+	for _, bad := range []FilterArgs{
+		{Domains: "re:("},
+		{Domains: "@" + filepath.Join(t.TempDir(), "missing")},
+		{Providers: "[abc"},
+		{Select: "payments"},
+	} {
+		if err := bad.prepare(); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
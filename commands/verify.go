@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/nameservers"
+	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/miekg/dns"
+	"github.com/urfave/cli/v2"
+)
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args VerifyArgs
+	return &cli.Command{
+		Name:  "verify",
+		Usage: "query each zone's authoritative nameservers and compare their answers to dnsconfig.js",
+		Action: func(ctx *cli.Context) error {
+			return exit(Verify(args))
+		},
+		Flags: args.flags(),
+		Description: `Query every address of every authoritative nameserver of each zone
+(as determined by "push") and report records that are missing, stale
+(served but not in dnsconfig.js), or have the wrong TTL.
+
+This is useful after a push to detect propagation delays, anycast nodes
+that disagree, and providers that accept changes but serve something else.`,
+	}
+}())
+
+// VerifyArgs contains all data/flags needed to run verify, independently of CLI.
+type VerifyArgs struct {
+	GetDNSConfigArgs
+	GetCredentialsArgs
+	FilterArgs
+	Nameservers string
+	Timeout     time.Duration
+}
+
+func (args *VerifyArgs) flags() []cli.Flag {
+	flags := args.GetDNSConfigArgs.flags()
+	flags = append(flags, args.GetCredentialsArgs.flags()...)
+	flags = append(flags, args.FilterArgs.flags()...)
+	flags = append(flags, &cli.StringFlag{
+		Name:        "nameservers",
+		Destination: &args.Nameservers,
+		Usage:       `Query these servers (comma separated list of host or host:port) instead of the zone's nameservers`,
+	})
+	flags = append(flags, &cli.DurationFlag{
+		Name:        "timeout",
+		Destination: &args.Timeout,
+		Value:       5 * time.Second,
+		Usage:       `Timeout of each DNS query`,
+	})
+	return flags
+}
+
+// verifyProblem is a difference between the desired records and the
+// answer of one server.
+type verifyProblem struct {
+	Server string // The server's name and address.
+	Kind   string // MISSING, STALE, TTL or ERROR
+	Msg    string
+}
+
+func (p verifyProblem) String() string {
+	return fmt.Sprintf("%s: %-7s %s", p.Server, p.Kind, p.Msg)
+}
+
+// Verify implements the verify subcommand.
+func Verify(args VerifyArgs) error {
+	out := printer.DefaultPrinter
+
+	if err := args.FilterArgs.prepare(); err != nil {
+		return err
+	}
+	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err != nil {
+		return err
+	}
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return err
+	}
+	if _, err := PInitializeProviders(cfg, providerConfigs, false); err != nil {
+		return err
+	}
+	errs := normalize.ValidateAndNormalizeConfig(cfg)
+	if PrintValidationErrors(errs) {
+		return errors.New("exiting due to validation errors")
+	}
+
+	client := &dns.Client{Timeout: args.Timeout}
+	var override []string
+	if args.Nameservers != "" {
+		override = strings.Split(args.Nameservers, ",")
+	}
+
+	var total int
+	for _, zone := range args.whichZones(cfg.Domains) {
+		out.StartDomain(zone.GetUniqueName())
+
+		nss, err := nameservers.DetermineNameserversForProviders(zone, whichProvidersToProcess(zone.DNSProviderInstances, args.Providers), true)
+		if err != nil {
+			out.Errorf("%s\n", err)
+			total++
+			continue
+		}
+		zone.Nameservers = nss
+		nameservers.AddNSRecords(zone)
+
+		servers := override
+		if servers == nil {
+			for _, ns := range nss {
+				servers = append(servers, ns.Name)
+			}
+		}
+		if len(servers) == 0 {
+			out.Warnf("No nameservers for %s\n", zone.GetUniqueName())
+			continue
+		}
+
+		problems, checked, err := verifyZone(zone, servers, client)
+		if err != nil {
+			out.Errorf("%s\n", err)
+			total++
+			continue
+		}
+		for _, p := range problems {
+			out.Printf("%s\n", p)
+		}
+		if len(problems) == 0 {
+			out.Printf("OK: %d records on %d server(s)\n", checked, len(servers))
+		}
+		total += len(problems)
+	}
+
+	if total != 0 {
+		return fmt.Errorf("verify found %d problem(s)", total)
+	}
+	return nil
+}
+
+// verifyZone queries each server for each of the zone's records and
+// returns the differences.  It also returns the number of records that
+// were checked.
+func verifyZone(dc *models.DomainConfig, servers []string, client *dns.Client) ([]verifyProblem, int, error) {
+	ignored, err := diff2.UnmanagedMatcher(dc.Unmanaged)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Group the desired records by label:type, in order.
+	models.Downcase(dc.Records)
+	models.CanonicalizeTargets(dc.Records, dc.Name)
+	var keys []models.RecordKey
+	desired := map[models.RecordKey]models.Records{}
+	var checked int
+	delegations := map[string]bool{}
+	for _, rec := range dc.Records {
+		if !verifiable(rec.Type) {
+			continue
+		}
+		k := rec.Key()
+		if _, ok := desired[k]; !ok {
+			keys = append(keys, k)
+		}
+		desired[k] = append(desired[k], rec)
+		checked++
+		if rec.Type == "NS" && k.NameFQDN != dc.Name {
+			delegations[k.NameFQDN] = true
+		}
+	}
+
+	var problems []verifyProblem
+	for _, server := range servers {
+		addrs, err := verifyServerAddrs(server)
+		if err != nil {
+			problems = append(problems, verifyProblem{Server: server, Kind: "ERROR", Msg: err.Error()})
+			continue
+		}
+		for _, addr := range addrs {
+			name := server
+			if addr != server {
+				name = fmt.Sprintf("%s (%s)", server, addr)
+			}
+			for _, k := range keys {
+				p, err := verifyRRSet(client, addr, dc.Name, k, desired[k], ignored, isDelegated(k, dc.Name, delegations))
+				if err != nil {
+					// The server can't be reached. Do not repeat the same
+					// error for every record.
+					problems = append(problems, verifyProblem{Server: name, Kind: "ERROR", Msg: err.Error()})
+					break
+				}
+				for _, msg := range p {
+					msg.Server = name
+					problems = append(problems, msg)
+				}
+			}
+		}
+	}
+	return problems, checked, nil
+}
+
+// isDelegated returns true if the records of k are at or below a
+// delegation (other than the DS records at the delegation, which the
+// parent serves): the NS records of the delegation and the glue.
+// Servers answer such queries with a referral.
+func isDelegated(k models.RecordKey, origin string, delegations map[string]bool) bool {
+	for name := k.NameFQDN; name != origin && name != ""; {
+		if delegations[name] {
+			return !(name == k.NameFQDN && k.Type == "DS")
+		}
+		_, parent, ok := strings.Cut(name, ".")
+		if !ok {
+			break
+		}
+		name = parent
+	}
+	return false
+}
+
+// verifiable returns true for the record types that can be compared to a
+// DNS answer.  Pseudo types (ALIAS, R53_ALIAS, etc.) and the SOA (whose
+// serial is managed by the provider) are skipped.
+func verifiable(rtype string) bool {
+	if rtype == "SOA" {
+		return false
+	}
+	_, ok := dns.StringToType[rtype]
+	return ok
+}
+
+// verifyServerAddrs returns the host:port of each address of a server.
+func verifyServerAddrs(server string) ([]string, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, "53"
+	}
+	if net.ParseIP(host) != nil {
+		return []string{net.JoinHostPort(host, port)}, nil
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip, port))
+	}
+	return addrs, nil
+}
+
+// verifyRRSet queries addr for one label:type and compares the answer to
+// the desired records.  If delegated is true, the records are expected in
+// a referral: the NS records in the authority section, and the glue in the
+// additional section.  Problems with the answer are returned as problems;
+// an error means that the server could not be queried.
+func verifyRRSet(client *dns.Client, addr string, origin string, k models.RecordKey, want models.Records, ignored func(*models.RecordConfig) bool, delegated bool) ([]verifyProblem, error) {
+	qtype := dns.StringToType[k.Type]
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(k.NameFQDN), qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(4096, false)
+
+	r, _, err := client.Exchange(m, addr)
+	if err == nil && r.Truncated {
+		tcp := *client
+		tcp.Net = "tcp"
+		r, _, err = tcp.Exchange(m, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		return []verifyProblem{{Kind: "ERROR", Msg: fmt.Sprintf("query %s %s: %s", k.NameFQDN, k.Type, dns.RcodeToString[r.Rcode])}}, nil
+	}
+	answer := r.Answer
+	if !r.Authoritative {
+		if !delegated || len(r.Answer) != 0 {
+			return []verifyProblem{{Kind: "ERROR", Msg: fmt.Sprintf("query %s %s: answer is not authoritative", k.NameFQDN, k.Type)}}, nil
+		}
+		// A referral.
+		answer = append(append([]dns.RR{}, r.Ns...), r.Extra...)
+	}
+
+	var got models.Records
+	for _, rr := range answer {
+		h := rr.Header()
+		if h.Rrtype != qtype || !strings.EqualFold(h.Name, dns.Fqdn(k.NameFQDN)) {
+			continue
+		}
+		rc, err := models.RRtoRC(rr, origin)
+		if err != nil {
+			return nil, err
+		}
+		got = append(got, &rc)
+	}
+	models.Downcase(got)
+	models.CanonicalizeTargets(got, origin)
+
+	var problems []verifyProblem
+	served := map[string]*models.RecordConfig{}
+	for _, rec := range got {
+		served[rec.ToComparableNoTTL()] = rec
+	}
+	wanted := map[string]bool{}
+	for _, rec := range want {
+		c := rec.ToComparableNoTTL()
+		wanted[c] = true
+		s, ok := served[c]
+		switch {
+		case !ok:
+			problems = append(problems, verifyProblem{Kind: "MISSING", Msg: verifyRecordString(rec)})
+		case s.TTL != rec.TTL:
+			problems = append(problems, verifyProblem{Kind: "TTL", Msg: fmt.Sprintf("%s (served ttl=%d)", verifyRecordString(rec), s.TTL)})
+		}
+	}
+	for _, rec := range got {
+		if !wanted[rec.ToComparableNoTTL()] && !ignored(rec) {
+			problems = append(problems, verifyProblem{Kind: "STALE", Msg: verifyRecordString(rec)})
+		}
+	}
+	return problems, nil
+}
+
+func verifyRecordString(rec *models.RecordConfig) string {
+	return fmt.Sprintf("%s %s %s ttl=%d", rec.GetLabelFQDN(), rec.Type, rec.ToComparableNoTTL(), rec.TTL)
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/miekg/dns"
+)
+
+// startTestDNSServer serves the records in zone (in zonefile format)
+// authoritatively and returns the server's address.
+func startTestDNSServer(t *testing.T, origin string, zone string) string {
+	t.Helper()
+	var rrs []dns.RR
+	zp := dns.NewZoneParser(strings.NewReader(zone), origin, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		// Names at or below a delegation get a referral, with the glue.
+		for _, rr := range rrs {
+			if ns, ok := rr.(*dns.NS); ok && !strings.EqualFold(ns.Hdr.Name, origin) && dns.IsSubDomain(ns.Hdr.Name, q.Name) && q.Qtype != dns.TypeDS {
+				m.Ns = append(m.Ns, rr)
+			}
+		}
+		if len(m.Ns) != 0 {
+			for _, rr := range rrs {
+				if a, ok := rr.(*dns.A); ok && dns.IsSubDomain(m.Ns[0].Header().Name, a.Hdr.Name) {
+					m.Extra = append(m.Extra, rr)
+				}
+			}
+			_ = w.WriteMsg(m)
+			return
+		}
+		m.Authoritative = true
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == q.Qtype && strings.EqualFold(rr.Header().Name, q.Name) {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func Test_verifyZone(t *testing.T) {
+	addr := startTestDNSServer(t, "example.com.", `
+$TTL 300
+@       IN A     1.2.3.4
+www     IN CNAME @
+txt 600 IN TXT   "hello world"
+mx      IN MX    10 mail.example.com.
+mx      IN MX    20 old.example.com.
+ign     IN A     10.0.0.1
+sub     IN NS    ns1.sub
+ns1.sub IN A     192.0.2.53
+zzz     IN A     1.2.3.4
+`)
+
+	dc := &models.DomainConfig{
+		Name: "example.com",
+		Records: models.Records{
+			testRecord("@", "A", "1.2.3.4", 300),
+			testRecord("@", "A", "1.2.3.5", 300),              // Missing.
+			testRecord("www", "CNAME", "example.com.", 300),   // OK.
+			testRecord("txt", "TXT", "hello world", 300),      // Wrong TTL.
+			testRecord("mx", "MX", "mail", 300),               // OK. (20 old is stale.)
+			testRecord("ign", "A", "10.0.0.2", 300),           // Missing. (10.0.0.1 is ignored.)
+			testRecord("alias", "ALIAS", "example.net.", 300), // Not verifiable.
+			testRecord("sub", "NS", "ns1.sub", 300),           // OK, in a referral.
+			testRecord("ns1.sub", "A", "192.0.2.53", 300),     // OK, glue.
+			testRecord("zzz", "A", "1.2.3.5", 300),            // Missing, after the delegation.
+		},
+		Unmanaged: []*models.UnmanagedConfig{{LabelPattern: "ign", TargetPattern: "10.0.0.1"}},
+	}
+
+	problems, checked, err := verifyZone(dc, []string{addr}, &dns.Client{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 9 {
+		t.Errorf("checked: got %d, want 9", checked)
+	}
+	var got []string
+	for _, p := range problems {
+		if p.Server != addr {
+			t.Errorf("server: got %q, want %q", p.Server, addr)
+		}
+		got = append(got, p.Kind+" "+p.Msg)
+	}
+	want := []string{
+		"MISSING example.com A 1.2.3.5 ttl=300",
+		"TTL txt.example.com TXT \"hello world\" ttl=300 (served ttl=600)",
+		"STALE mx.example.com MX 20 old.example.com. ttl=300",
+		"MISSING ign.example.com A 10.0.0.2 ttl=300",
+		"MISSING zzz.example.com A 1.2.3.5 ttl=300",
+		"STALE zzz.example.com A 1.2.3.4 ttl=300",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -103,6 +104,20 @@ func NameserversToStrings(nss []*Nameserver) (s []string) {
 type Correction struct {
 	F   func() error `json:"-"`
 	Msg string
+
+	// FC is like F but can be cancelled. It is optional. If it is set, F
+	// must also be set since F != nil is how actions are distinguished from
+	// messages. See NewCorrectionContext.
+	FC func(context.Context) error `json:"-"`
+}
+
+// NewCorrectionContext returns a Correction whose function can be cancelled.
+func NewCorrectionContext(msg string, fc func(context.Context) error) *Correction {
+	return &Correction{
+		Msg: msg,
+		F:   func() error { return fc(context.Background()) },
+		FC:  fc,
+	}
 }
 
 // DomainContainingFQDN finds the best domain from the dns config for the given record fqdn.
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DNSProviderContext may be implemented by a DNSProvider whose API calls
+// can be cancelled.  Providers that do not implement it are adapted by
+// GetZoneRecordsContext and GetZoneRecordsCorrectionsContext.
+type DNSProviderContext interface {
+	GetZoneRecordsContext(ctx context.Context, domain string, meta map[string]string) (Records, error)
+	GetZoneRecordsCorrectionsContext(ctx context.Context, dc *DomainConfig, existing Records) ([]*Correction, int, error)
+}
+
+// RegistrarContext may be implemented by a Registrar whose API calls can be
+// cancelled.
+type RegistrarContext interface {
+	GetRegistrarCorrectionsContext(ctx context.Context, dc *DomainConfig) ([]*Correction, error)
+}
+
+// The adapters below call the context-aware method if the provider has
+// one.  Otherwise they run the plain method in a goroutine and return
+// ctx.Err() as soon as ctx is done.  The plain method can not be stopped;
+// it keeps running in the background and its result is discarded.  This
+// is enough to make sure that a hung API can not block dnscontrol forever.
+// The error then wraps ErrAbandoned, so that the caller knows not to use the
+// provider again while the call may still be running.
+
+// ErrAbandoned is wrapped in the error of a call that was given up on
+// while it was still running.
+var ErrAbandoned = errors.New("the call is still running")
+
+// GetZoneRecordsContext calls p.GetZoneRecords, or its context-aware variant.
+func GetZoneRecordsContext(ctx context.Context, p DNSProvider, domain string, meta map[string]string) (Records, error) {
+	if pc, ok := p.(DNSProviderContext); ok {
+		return pc.GetZoneRecordsContext(ctx, domain, meta)
+	}
+	return CallContext(ctx, func() (Records, error) {
+		return p.GetZoneRecords(domain, meta)
+	})
+}
+
+// GetZoneRecordsCorrectionsContext calls p.GetZoneRecordsCorrections, or
+// its context-aware variant.
+func GetZoneRecordsCorrectionsContext(ctx context.Context, p DNSProvider, dc *DomainConfig, existing Records) ([]*Correction, int, error) {
+	if pc, ok := p.(DNSProviderContext); ok {
+		return pc.GetZoneRecordsCorrectionsContext(ctx, dc, existing)
+	}
+	type result struct {
+		corrections []*Correction
+		count       int
+	}
+	r, err := CallContext(ctx, func() (result, error) {
+		c, n, err := p.GetZoneRecordsCorrections(dc, existing)
+		return result{c, n}, err
+	})
+	return r.corrections, r.count, err
+}
+
+// GetNameserversContext calls p.GetNameservers.
+func GetNameserversContext(ctx context.Context, p DNSProvider, domain string) ([]*Nameserver, error) {
+	return CallContext(ctx, func() ([]*Nameserver, error) {
+		return p.GetNameservers(domain)
+	})
+}
+
+// GetRegistrarCorrectionsContext calls r.GetRegistrarCorrections, or its
+// context-aware variant.
+func GetRegistrarCorrectionsContext(ctx context.Context, r Registrar, dc *DomainConfig) ([]*Correction, error) {
+	if rc, ok := r.(RegistrarContext); ok {
+		return rc.GetRegistrarCorrectionsContext(ctx, dc)
+	}
+	return CallContext(ctx, func() ([]*Correction, error) {
+		return r.GetRegistrarCorrections(dc)
+	})
+}
+
+// RunContext runs the correction.  It uses FC if it is set, otherwise F is
+// adapted as described above.
+func (c *Correction) RunContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err // Do not start anything new.
+	}
+	if c.FC != nil {
+		return c.FC(ctx)
+	}
+	_, err := CallContext(ctx, func() (struct{}, error) {
+		return struct{}{}, c.F()
+	})
+	return err
+}
+
+// CallContext runs f and returns its result, or an error that wraps both
+// ctx.Err() and ErrAbandoned if ctx is done first.  It adapts provider
+// methods that do not accept a context.
+func CallContext[T any](ctx context.Context, f func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1) // Buffered so the goroutine never blocks.
+	go func() {
+		v, err := f()
+		ch <- result{v, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, fmt.Errorf("%w (%w)", ctx.Err(), ErrAbandoned)
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hungProvider never returns from GetZoneRecords.
+type hungProvider struct{ release chan struct{} }
+
+func (p *hungProvider) GetNameservers(string) ([]*Nameserver, error) { return nil, nil }
+func (p *hungProvider) GetZoneRecords(string, map[string]string) (Records, error) {
+	<-p.release
+	return nil, nil
+}
+
+func (p *hungProvider) GetZoneRecordsCorrections(*DomainConfig, Records) ([]*Correction, int, error) {
+	return nil, 0, nil
+}
+
+// ctxProvider implements DNSProviderContext.
+type ctxProvider struct{ hungProvider }
+
+func (p *ctxProvider) GetZoneRecordsContext(ctx context.Context, _ string, _ map[string]string) (Records, error) {
+	return Records{{Type: "A"}}, ctx.Err()
+}
+
+func (p *ctxProvider) GetZoneRecordsCorrectionsContext(context.Context, *DomainConfig, Records) ([]*Correction, int, error) {
+	return nil, 0, nil
+}
+
+func TestGetZoneRecordsContext(t *testing.T) {
+	hung := &hungProvider{release: make(chan struct{})}
+	defer close(hung.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := GetZoneRecordsContext(ctx, hung, "example.com", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("hung provider: got %v, want DeadlineExceeded", err)
+	}
+
+	recs, err := GetZoneRecordsContext(context.Background(), &ctxProvider{}, "example.com", nil)
+	if err != nil || len(recs) != 1 {
+		t.Errorf("context-aware provider: got %v, %v", recs, err)
+	}
+}
+
+func TestCorrectionRunContext(t *testing.T) {
+	var ran bool
+	c := NewCorrectionContext("test", func(ctx context.Context) error {
+		ran = true
+		return ctx.Err()
+	})
+	if c.F == nil {
+		t.Fatal("NewCorrectionContext did not set F")
+	}
+	if err := c.RunContext(context.Background()); err != nil || !ran {
+		t.Errorf("RunContext: ran=%v err=%v", ran, err)
+	}
+
+	// Nothing is started once the context is done.
+	ran = false
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.RunContext(ctx); !errors.Is(err, context.Canceled) || ran {
+		t.Errorf("cancelled RunContext: ran=%v err=%v", ran, err)
+	}
+
+	// A plain F is abandoned when the deadline expires.
+	release := make(chan struct{})
+	defer close(release)
+	plain := &Correction{Msg: "plain", F: func() error { <-release; return nil }}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := plain.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("plain RunContext: got %v, want DeadlineExceeded", err)
+	}
+}
@@ -23,6 +23,12 @@ type UnmanagedConfig struct {
 	// Glob pattern for matching targets.
 	TargetPattern string    `json:"target_pattern,omitempty"`
 	TargetGlob    glob.Glob `json:"-"` // Compiled version
+
+	// Invert makes the rule match the records that do NOT match the
+	// patterns. It is set by push --types and --labels, so that the records
+	// that were not selected are left as they are. dnsconfig.js never sets
+	// it.
+	Invert bool `json:"-"`
 }
 
 // Uncomment to use:
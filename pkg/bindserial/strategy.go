@@ -0,0 +1,129 @@
+package bindserial
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A serial strategy picks the next SOA serial number of a zone. Providers
+// that write SOA records let users choose one with a "serial_strategy"
+// setting:
+//
+//	date             yyyymmddvv: today's date and a version number (default)
+//	unixtime         the current time in seconds since 1970
+//	increment        the old serial plus one
+//	git-commit-time  the time of the last git commit, in seconds since 1970
+//
+// Serials are compared using RFC 1982 serial number arithmetic, so they may
+// wrap around. If the strategy would not move the serial forward (for
+// example, because it was already updated today, or jumped ahead of the
+// clock), the old serial is incremented instead.
+
+// Strategies lists the valid serial strategies.
+var Strategies = []string{"date", "unixtime", "increment", "git-commit-time"}
+
+// DefaultStrategy is the strategy used if none is set.
+const DefaultStrategy = "date"
+
+// CheckStrategy returns an error if strategy is not a valid strategy. An
+// empty strategy is valid: it is the default.
+func CheckStrategy(strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+	for _, s := range Strategies {
+		if s == strategy {
+			return nil
+		}
+	}
+	return fmt.Errorf("serial_strategy (%v) must be one of: %s", strategy, strings.Join(Strategies, ", "))
+}
+
+// Less returns true if s1 is before s2 in RFC 1982 serial number
+// arithmetic. Serials that are exactly 2^31 apart are not comparable: both
+// Less(s1, s2) and Less(s2, s1) are false.
+func Less(s1, s2 uint32) bool {
+	return s1 != s2 && s2-s1 < 1<<31
+}
+
+// Increment returns the serial after s. It wraps around, skipping 0,
+// which some software treats as "no serial".
+func Increment(s uint32) uint32 {
+	s++
+	if s == 0 {
+		s = 1
+	}
+	return s
+}
+
+// Next returns the serial that follows old according to strategy. now is
+// the current time. If ForcedValue is set, it is returned instead.
+func Next(strategy string, old uint32, now time.Time) (uint32, error) {
+	if ForcedValue != 0 {
+		// https://github.com/StackExchange/dnscontrol/issues/1859
+		// User needs to have reproducible builds and BIND generates
+		return uint32(ForcedValue), nil
+	}
+
+	var candidate uint32
+	switch strategy {
+	case "", "date":
+		today, err := strconv.ParseUint(now.UTC().Format("20060102"), 10, 32)
+		if err != nil || today*100 > 1<<32-1 {
+			return 0, fmt.Errorf("serial for %s won't fit in 32 bits", now.UTC().Format("2006-01-02"))
+		}
+		candidate = uint32(today * 100)
+	case "unixtime":
+		candidate = uint32(now.Unix())
+	case "increment":
+		candidate = old
+	case "git-commit-time":
+		t, err := gitCommitTime()
+		if err != nil {
+			return 0, err
+		}
+		candidate = uint32(t)
+	default:
+		return 0, CheckStrategy(strategy)
+	}
+
+	if Less(old, candidate) {
+		return candidate, nil
+	}
+	return Increment(old), nil
+}
+
+// CheckForcedValue returns an error if v can not be used as ForcedValue.
+func CheckForcedValue(v int64) error {
+	if v < 0 || v > 1<<32-1 {
+		return fmt.Errorf("--bindserial %d is not a valid serial: it must be between 0 (not forced) and %d", v, uint32(1<<32-1))
+	}
+	return nil
+}
+
+var (
+	gitCommitOnce sync.Once
+	gitCommitSecs int64
+	gitCommitErr  error
+)
+
+// gitCommitTime returns the time of the last commit of the git repository
+// of the current directory, in seconds since 1970. It runs git once.
+func gitCommitTime() (int64, error) {
+	gitCommitOnce.Do(func() {
+		out, err := exec.Command("git", "log", "-1", "--format=%ct").Output()
+		if err != nil {
+			gitCommitErr = fmt.Errorf("serial_strategy git-commit-time: git log: %w", err)
+			return
+		}
+		gitCommitSecs, gitCommitErr = strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if gitCommitErr != nil {
+			gitCommitErr = fmt.Errorf("serial_strategy git-commit-time: %w", gitCommitErr)
+		}
+	})
+	return gitCommitSecs, gitCommitErr
+}
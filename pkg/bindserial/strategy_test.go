@@ -0,0 +1,80 @@
+package bindserial
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLess(t *testing.T) {
+	for _, tt := range []struct {
+		s1, s2 uint32
+		want   bool
+	}{
+		{1, 2, true},
+		{2, 1, false},
+		{5, 5, false},
+		{0xFFFFFFFF, 0, true}, // Wraps around.
+		{0xFFFFFFFF, 5, true},
+		{5, 0xFFFFFFFF, false},
+		{0, 1 << 31, false}, // Not comparable.
+		{1 << 31, 0, false},
+		{0, 1<<31 - 1, true},
+	} {
+		if got := Less(tt.s1, tt.s2); got != tt.want {
+			t.Errorf("Less(%d, %d) = %v, want %v", tt.s1, tt.s2, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	unix := uint32(now.Unix())
+	for _, tt := range []struct {
+		strategy string
+		old      uint32
+		want     uint32
+	}{
+		{"", 0, 2024031500},
+		{"date", 2024031500, 2024031501},
+		{"date", 2024031499, 2024031500},
+		{"date", 2099000000, 2099000001}, // Ahead of the date: increment.
+		{"date", 0xFFFFFFFF, 2024031500}, // Wraps around to today.
+		{"unixtime", 0, unix},
+		{"unixtime", 2024031500, 2024031501}, // A date serial is ahead of the clock.
+		{"unixtime", unix, unix + 1},
+		{"unixtime", unix + 10, unix + 11},
+		{"increment", 41, 42},
+		{"increment", 0xFFFFFFFF, 1},
+		{"increment", 0, 1},
+		// More than 2^31 behind: the serial can't jump there, so it is
+		// incremented.
+		{"unixtime", unix - 1<<31, unix - 1<<31 + 1},
+	} {
+		got, err := Next(tt.strategy, tt.old, now)
+		if err != nil {
+			t.Errorf("Next(%q, %d): %v", tt.strategy, tt.old, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Next(%q, %d) = %d, want %d", tt.strategy, tt.old, got, tt.want)
+		}
+	}
+
+	if _, err := Next("lunar", 1, now); err == nil {
+		t.Error("Next with an unknown strategy succeeded")
+	}
+
+	ForcedValue = 3000000000
+	defer func() { ForcedValue = 0 }()
+	if got, _ := Next("date", 1, now); got != 3000000000 {
+		t.Errorf("Next with ForcedValue = %d, want 3000000000", got)
+	}
+}
+
+func TestCheckForcedValue(t *testing.T) {
+	for v, ok := range map[int64]bool{0: true, 1: true, 4294967295: true, 4294967296: false, -1: false} {
+		if err := CheckForcedValue(v); (err == nil) != ok {
+			t.Errorf("CheckForcedValue(%d) = %v", v, err)
+		}
+	}
+}
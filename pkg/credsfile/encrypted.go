@@ -0,0 +1,182 @@
+package credsfile
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// An encrypted creds file is either:
+//
+//   - A file encrypted with age (https://age-encryption.org), binary or
+//     armored.
+//   - A creds file encrypted by SOPS (https://getsops.io) with an age key:
+//     the keys are readable, the values are encrypted, and the "sops" key
+//     holds the data key, encrypted with age.
+//
+// The age identities (private keys) are found the same way as SOPS finds
+// them: in $SOPS_AGE_KEY, in the file $SOPS_AGE_KEY_FILE, or in the file
+// sops/age/keys.txt in the user's configuration directory.
+
+const (
+	ageHeader        = "age-encryption.org/v1\n"
+	ageArmoredHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+// isAgeEncrypted returns true if dat is an age-encrypted file.
+func isAgeEncrypted(dat []byte) bool {
+	dat = bytes.TrimLeft(dat, " \t\r\n")
+	return bytes.HasPrefix(dat, []byte(ageHeader)) || bytes.HasPrefix(dat, []byte(ageArmoredHeader))
+}
+
+// decryptAge decrypts an age-encrypted file.
+func decryptAge(dat []byte) ([]byte, error) {
+	identities, err := ageIdentities()
+	if err != nil {
+		return nil, err
+	}
+	var src io.Reader = bytes.NewReader(dat)
+	if bytes.HasPrefix(bytes.TrimLeft(dat, " \t\r\n"), []byte(ageArmoredHeader)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimLeft(dat, " \t\r\n")))
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting with age: %w", err)
+	}
+	return io.ReadAll(r)
+}
+
+// ageIdentities returns the age identities that may decrypt creds files.
+func ageIdentities() ([]age.Identity, error) {
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("SOPS_AGE_KEY: %w", err)
+		}
+		return ids, nil
+	}
+
+	fname := os.Getenv("SOPS_AGE_KEY_FILE")
+	if fname == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("no age key: set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+		}
+		fname = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("no age key: set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE, or create %s: %w", fname, err)
+	}
+	defer f.Close()
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	return ids, nil
+}
+
+// sopsMetadata is the part of the "sops" key that is needed to decrypt the
+// values.
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `json:"recipient"`
+		Enc       string `json:"enc"`
+	} `json:"age"`
+}
+
+// sopsValue matches a value encrypted by SOPS.
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]*),tag:([^,]*),type:([^\]]*)\]$`)
+
+// decryptSops decrypts the values of a creds file encrypted by SOPS. meta
+// is the value of its "sops" key.
+//
+// The MAC of the file (meta's "mac") is not checked: each value is
+// authenticated with its path, but values that were removed or added in
+// cleartext are not detected.
+func decryptSops(m map[string]map[string]string, meta json.RawMessage) error {
+	var md sopsMetadata
+	if err := json.Unmarshal(meta, &md); err != nil {
+		return fmt.Errorf("invalid sops metadata: %w", err)
+	}
+	if len(md.Age) == 0 {
+		return errors.New("sops: only files encrypted with age keys are supported")
+	}
+
+	identities, err := ageIdentities()
+	if err != nil {
+		return err
+	}
+	var dataKey []byte
+	for _, a := range md.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(a.Enc)), identities...)
+		if err != nil {
+			continue // Try the next recipient.
+		}
+		if dataKey, err = io.ReadAll(r); err == nil {
+			break
+		}
+	}
+	if dataKey == nil {
+		return errors.New("sops: none of the age keys can decrypt the data key")
+	}
+
+	for name, keys := range m {
+		for k, v := range keys {
+			if !strings.HasPrefix(v, "ENC[") {
+				continue
+			}
+			// The path of the value is authenticated, so that encrypted
+			// values can not be moved.
+			plain, err := decryptSopsValue(dataKey, v, name+":"+k+":")
+			if err != nil {
+				return fmt.Errorf("sops: %q %q: %w", name, k, err)
+			}
+			keys[k] = plain
+		}
+	}
+	return nil
+}
+
+// decryptSopsValue decrypts a single ENC[AES256_GCM,...] value.
+func decryptSopsValue(key []byte, value string, aad string) (string, error) {
+	sm := sopsValue.FindStringSubmatch(value)
+	if sm == nil {
+		return "", errors.New("invalid encrypted value")
+	}
+	var parts [3][]byte
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(sm[i+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return "", errors.New("can not decrypt value (wrong key, or the file was modified)")
+	}
+	return string(plain), nil
+}
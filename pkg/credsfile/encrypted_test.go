@@ -0,0 +1,145 @@
+package credsfile
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const testCreds = `{
+  // A comment.
+  "cloudflare": {"TYPE": "CLOUDFLAREAPI", "apitoken": "t0ken"},
+  "bind": {"TYPE": "BIND", "directory": "zones"}
+}`
+
+func ageEncrypt(t *testing.T, plain []byte, armored bool, r age.Recipient) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var dst io.WriteCloser = nopCloser{&buf}
+	if armored {
+		dst = armor.NewWriter(&buf)
+	}
+	w, err := age.Encrypt(dst, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(plain)
+	w.Close()
+	dst.Close()
+	return buf.Bytes()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// sopsEncryptValue encrypts a value the way SOPS does.
+func sopsEncryptValue(t *testing.T, key []byte, value, aad string) string {
+	t.Helper()
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCMWithNonceSize(block, 32)
+	iv := make([]byte, 32)
+	rand.Read(iv)
+	out := gcm.Seal(nil, iv, []byte(value), []byte(aad))
+	data, tag := out[:len(out)-16], out[len(out)-16:]
+	enc := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]", enc(data), enc(iv), enc(tag))
+}
+
+func writeTemp(t *testing.T, dat []byte) string {
+	t.Helper()
+	fname := filepath.Join(t.TempDir(), "creds.json")
+	if err := os.WriteFile(fname, dat, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func checkCreds(t *testing.T, got map[string]map[string]string) {
+	t.Helper()
+	if got["cloudflare"]["apitoken"] != "t0ken" || got["bind"]["directory"] != "zones" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestLoadProviderConfigs_age(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	other, _ := age.GenerateX25519Identity()
+
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	os.WriteFile(keyFile, []byte("# created: today\n"+id.String()+"\n"), 0o600)
+
+	for _, armored := range []bool{false, true} {
+		fname := writeTemp(t, ageEncrypt(t, []byte(testCreds), armored, id.Recipient()))
+
+		t.Setenv("SOPS_AGE_KEY", id.String())
+		got, err := LoadProviderConfigs(fname)
+		if err != nil {
+			t.Fatalf("armored=%v: %v", armored, err)
+		}
+		checkCreds(t, got)
+
+		t.Setenv("SOPS_AGE_KEY", "")
+		t.Setenv("SOPS_AGE_KEY_FILE", keyFile)
+		got, err = LoadProviderConfigs(fname)
+		if err != nil {
+			t.Fatalf("armored=%v, key file: %v", armored, err)
+		}
+		checkCreds(t, got)
+
+		t.Setenv("SOPS_AGE_KEY", other.String())
+		if _, err := LoadProviderConfigs(fname); err == nil || !strings.Contains(err.Error(), "no identity matched") {
+			t.Errorf("armored=%v, wrong key: got %v", armored, err)
+		}
+	}
+}
+
+func TestLoadProviderConfigs_sops(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	t.Setenv("SOPS_AGE_KEY", id.String())
+
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+	encKey := ageEncrypt(t, dataKey, true, id.Recipient())
+
+	file := map[string]any{
+		"cloudflare": map[string]string{
+			"TYPE":     sopsEncryptValue(t, dataKey, "CLOUDFLAREAPI", "cloudflare:TYPE:"),
+			"apitoken": sopsEncryptValue(t, dataKey, "t0ken", "cloudflare:apitoken:"),
+		},
+		"bind": map[string]string{"TYPE": "BIND", "directory": "zones"},
+		"sops": map[string]any{
+			"age":          []map[string]string{{"recipient": id.Recipient().String(), "enc": string(encKey)}},
+			"lastmodified": "2025-01-01T00:00:00Z",
+			"version":      "3.9.0",
+		},
+	}
+	dat, _ := json.Marshal(file)
+	got, err := LoadProviderConfigs(writeTemp(t, dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCreds(t, got)
+	if _, ok := got["sops"]; ok {
+		t.Error("the sops metadata is returned as a provider")
+	}
+
+	// A value that was moved to another key does not decrypt.
+	file["cloudflare"].(map[string]string)["apitoken"] = sopsEncryptValue(t, dataKey, "t0ken", "cloudflare:other:")
+	dat, _ = json.Marshal(file)
+	if _, err := LoadProviderConfigs(writeTemp(t, dat)); err == nil || !strings.Contains(err.Error(), `"cloudflare" "apitoken"`) {
+		t.Errorf("moved value: got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package credsfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/DisposaBoy/JsonConfigReader"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// The format of a creds file is selected by its extension: .yaml or .yml
+// for YAML, .toml for TOML, anything else for JSON (with comments and
+// trailing commas). The extension of an age-encrypted file is the one
+// before ".age", as in "creds.yaml.age".
+
+// credsFormat returns "yaml", "toml" or "json".
+func credsFormat(fname string) string {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(fname, ".age"))) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	return "json"
+}
+
+// isDataFile returns true if fname is a creds file that must be read
+// even if it is executable: a JSON, YAML or TOML file, a file encrypted
+// with age ("creds.json.age") or a file encrypted by SOPS
+// ("creds.sops.json", "creds.sops.env").
+func isDataFile(fname string) bool {
+	base := filepath.Base(fname)
+	return strings.HasSuffix(base, ".json") ||
+		strings.HasSuffix(base, ".age") ||
+		strings.Contains(base, ".sops.") ||
+		credsFormat(fname) != "json"
+}
+
+// decodeCredsFile parses a creds file into the raw JSON of each entry.
+func decodeCredsFile(fname string, dat []byte) (map[string]json.RawMessage, error) {
+	var raw map[string]json.RawMessage
+	switch credsFormat(fname) {
+	case "yaml":
+		var m map[string]any
+		if err := yaml.Unmarshal(dat, &m); err != nil {
+			return nil, err
+		}
+		return toRawEntries(m)
+	case "toml":
+		var m map[string]any
+		if err := toml.Unmarshal(dat, &m); err != nil {
+			return nil, err
+		}
+		return toRawEntries(m)
+	}
+	r := JsonConfigReader.New(strings.NewReader(string(dat)))
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// toRawEntries converts the entries of a YAML or TOML file to JSON. Numbers
+// and booleans become strings, as they would be quoted in creds.json.
+func toRawEntries(m map[string]any) (map[string]json.RawMessage, error) {
+	raw := make(map[string]json.RawMessage, len(m))
+	for name, v := range m {
+		if name == "sops" {
+			// The SOPS metadata is passed through as is.
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", name, err)
+			}
+			raw[name] = b
+			continue
+		}
+		entry, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%q: expected a table of keys, got %T", name, v)
+		}
+		keys := make(map[string]string, len(entry))
+		for k, val := range entry {
+			switch val := val.(type) {
+			case nil:
+				keys[k] = ""
+			case string:
+				keys[k] = val
+			case map[string]any, []any:
+				return nil, fmt.Errorf("%q %q: values must be strings, numbers or booleans", name, k)
+			default:
+				keys[k] = fmt.Sprint(val)
+			}
+		}
+		b, err := json.Marshal(keys)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		}
+		raw[name] = b
+	}
+	return raw, nil
+}
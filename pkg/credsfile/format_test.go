@@ -0,0 +1,101 @@
+package credsfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProviderConfigs_formats(t *testing.T) {
+	tests := map[string]string{
+		"creds.yaml": `
+cloudflare:
+  TYPE: CLOUDFLAREAPI
+  apitoken: t0ken
+bind:
+  TYPE: BIND
+  directory: zones
+porkbun:
+  TYPE: PORKBUN
+  max_attempts: 5
+  empty:
+`,
+		"creds.yml": `{"cloudflare": {"TYPE": "CLOUDFLAREAPI", "apitoken": "t0ken"}, "bind": {"TYPE": "BIND", "directory": "zones"}, "porkbun": {"max_attempts": 5, "empty": null}}`,
+		"creds.toml": `
+[cloudflare]
+TYPE = "CLOUDFLAREAPI"
+apitoken = "t0ken"
+
+[bind]
+TYPE = "BIND"
+directory = "zones"
+
+[porkbun]
+max_attempts = 5
+empty = ""
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), name)
+			os.WriteFile(fname, []byte(content), 0o600)
+			got, err := LoadProviderConfigs(fname)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkCreds(t, got)
+			if v, ok := got["porkbun"]["max_attempts"]; !ok || v != "5" {
+				t.Errorf("max_attempts: got %q", v)
+			}
+			if v, ok := got["porkbun"]["empty"]; !ok || v != "" {
+				t.Errorf("empty: got %q", v)
+			}
+		})
+	}
+}
+
+func TestLoadProviderConfigs_formatErrors(t *testing.T) {
+	tests := map[string]string{
+		"nested.yaml": "cloudflare:\n  TYPE: CLOUDFLAREAPI\n  zones: [a, b]\n",
+		"scalar.yaml": "cloudflare: CLOUDFLAREAPI\n",
+		"nested.toml": "[cloudflare]\nTYPE = \"CLOUDFLAREAPI\"\n[cloudflare.zones]\na = 1\n",
+		"bad.toml":    "[cloudflare\n",
+	}
+	for name, content := range tests {
+		fname := filepath.Join(t.TempDir(), name)
+		os.WriteFile(fname, []byte(content), 0o600)
+		if _, err := LoadProviderConfigs(fname); err == nil || !strings.Contains(err.Error(), fname) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func Test_credsFormat(t *testing.T) {
+	// The format of an age-encrypted file is the extension before ".age".
+	if got := credsFormat("creds.yaml.age"); got != "yaml" {
+		t.Errorf("creds.yaml.age: got %q", got)
+	}
+	if got := credsFormat("creds.json.age"); got != "json" {
+		t.Errorf("creds.json.age: got %q", got)
+	}
+}
+
+func Test_isDataFile(t *testing.T) {
+	for fname, want := range map[string]bool{
+		"creds.json":          true,
+		"creds.yaml":          true,
+		"creds.toml":          true,
+		"creds.json.age":      true,
+		"dir/creds.yaml.age":  true,
+		"creds.sops.json":     true,
+		"creds.sops.env":      true,
+		"get-creds.sh":        false,
+		"creds":               false,
+		"sops.d/get-creds.sh": false,
+	} {
+		if got := isDataFile(fname); got != want {
+			t.Errorf("isDataFile(%q) = %v, want %v", fname, got, want)
+		}
+	}
+}
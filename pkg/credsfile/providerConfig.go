@@ -1,8 +1,15 @@
 // Package credsfile provides functions for reading and parsing the provider credentials json file.
+// Files ending in .yaml, .yml or .toml are parsed as YAML or TOML instead.
 // It cleans nonstandard json features (comments and trailing commas), as well as replaces environment variable placeholders with
 // their environment variable equivalents. To reference an environment variable in your json file, simply use values in this format:
 //
 //	"key"="$ENV_VAR_NAME"
+//
+// Secret references may also appear anywhere in a value (see RegisterSecretResolver):
+//
+//	"key"="${env:ENV_VAR_NAME}"
+//	"key"="${file:/run/secrets/token}"
+//	"key"="${cmd:pass show dns/cf}"
 package credsfile
 
 import (
@@ -14,7 +21,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/DisposaBoy/JsonConfigReader"
 	"github.com/TomOnTime/utfutil"
 	"github.com/google/shlex"
 )
@@ -27,8 +33,8 @@ func LoadProviderConfigs(fname string) (map[string]map[string]string, error) {
 	var err error
 	filesIsExecutable := strings.HasPrefix(fname, "!") || isExecutable(fname)
 
-	if filesIsExecutable && !strings.HasSuffix(fname, ".json") {
-		// file is executable and is not a .json (needed because in Windows WSL all files are executable).
+	if filesIsExecutable && !isDataFile(fname) {
+		// file is executable and is not a .json, .yaml or .toml (needed because in Windows WSL all files are executable).
 		dat, err = executeCredsFile(strings.TrimPrefix(fname, "!"))
 		if err != nil {
 			return nil, err
@@ -41,13 +47,32 @@ func LoadProviderConfigs(fname string) (map[string]map[string]string, error) {
 		}
 	}
 
-	s := string(dat)
-	r := JsonConfigReader.New(strings.NewReader(s))
-	err = json.NewDecoder(r).Decode(&results)
+	if isAgeEncrypted(dat) {
+		dat, err = decryptAge(dat)
+		if err != nil {
+			return nil, fmt.Errorf("failed decrypting provider credentials file %v: %w", fname, err)
+		}
+	}
+
+	raw, err := decodeCredsFile(fname, dat)
 	if err != nil {
 		return nil, fmt.Errorf("failed parsing provider credentials file %v: %w", fname, err)
 	}
-	if err = replaceEnvVars(results); err != nil {
+	sopsMeta, isSops := raw["sops"]
+	delete(raw, "sops")
+	for name, v := range raw {
+		var keys map[string]string
+		if err := json.Unmarshal(v, &keys); err != nil {
+			return nil, fmt.Errorf("failed parsing provider credentials file %v: %q: %w", fname, name, err)
+		}
+		results[name] = keys
+	}
+	if isSops {
+		if err = decryptSops(results, sopsMeta); err != nil {
+			return nil, fmt.Errorf("failed decrypting provider credentials file %v: %w", fname, err)
+		}
+	}
+	if err = resolveSecrets(results); err != nil {
 		return nil, err
 	}
 
@@ -74,6 +99,9 @@ func isExecutable(filename string) bool {
 }
 
 func readCredsFile(filename string) ([]byte, error) {
+	if dat, err := os.ReadFile(filename); err == nil && isAgeEncrypted(dat) {
+		return dat, nil // Binary. Do not convert it.
+	}
 	dat, err := utfutil.ReadFile(filename, utfutil.POSIX)
 	if err != nil {
 		// no creds file is ok. Bind requires nothing for example. Individual providers will error if things not found.
@@ -105,16 +133,3 @@ func fileExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return !errors.Is(err, os.ErrNotExist)
 }
-
-func replaceEnvVars(m map[string]map[string]string) error {
-	for _, keys := range m {
-		for k, v := range keys {
-			if strings.HasPrefix(v, "$") {
-				env := v[1:]
-				newVal := os.Getenv(env)
-				keys[k] = newVal
-			}
-		}
-	}
-	return nil
-}
@@ -0,0 +1,148 @@
+package credsfile
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// SecretResolver returns the value of a secret reference. For
+// "${file:/run/secrets/token}", it is called with "/run/secrets/token".
+type SecretResolver func(ref string) (string, error)
+
+// secretResolvers stores the resolver for each kind of reference.
+var secretResolvers = map[string]SecretResolver{}
+
+// RegisterSecretResolver adds a kind of secret reference: "${kind:ref}" is
+// replaced by the value that r returns for ref.
+func RegisterSecretResolver(kind string, r SecretResolver) {
+	if _, ok := secretResolvers[kind]; ok {
+		log.Fatalf("Cannot register secret resolver %q multiple times", kind)
+	}
+	secretResolvers[kind] = r
+}
+
+func init() {
+	RegisterSecretResolver("env", resolveEnv)
+	RegisterSecretResolver("file", resolveFile)
+	RegisterSecretResolver("cmd", resolveCmd)
+}
+
+func resolveEnv(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+func resolveFile(name string) (string, error) {
+	dat, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(dat), "\r\n"), nil
+}
+
+func resolveCmd(command string) (string, error) {
+	args, err := shlex.Split(command)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// secretRef matches "${kind:ref}", and "$${" (an escaped "${").
+var secretRef = regexp.MustCompile(`\$\$\{|\$\{([a-z][a-z0-9_]*):([^}]*)\}`)
+
+// resolveSecrets replaces the environment variables ("$ENV_VAR_NAME", the
+// whole value) and the secret references in the values of m.  Each distinct
+// reference is resolved once.  Both are replaced in one pass: the value of
+// an environment variable or a secret is never scanned for references.
+func resolveSecrets(m map[string]map[string]string) error {
+	cache := map[string]string{}
+
+	// Sorted, so that the first error is always the same.
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		keys := m[name]
+		for k, v := range keys {
+			if strings.HasPrefix(v, "$") && !secretRef.MatchString(v) {
+				keys[k] = os.Getenv(v[1:])
+				continue
+			}
+			if !strings.Contains(v, "${") {
+				continue
+			}
+			nv, err := expandSecrets(v, cache)
+			if err != nil {
+				return fmt.Errorf("creds.json: %q %q: %w", name, k, err)
+			}
+			keys[k] = nv
+		}
+	}
+	return nil
+}
+
+// expandSecrets replaces the secret references in v.
+func expandSecrets(v string, cache map[string]string) (string, error) {
+	var firstErr error
+	out := secretRef.ReplaceAllStringFunc(v, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		if firstErr != nil {
+			return ""
+		}
+		if val, ok := cache[match]; ok {
+			return val
+		}
+		sm := secretRef.FindStringSubmatch(match)
+		kind, ref := sm[1], sm[2]
+		r, ok := secretResolvers[kind]
+		if !ok {
+			firstErr = fmt.Errorf("%s: unknown kind of secret %q (known: %s)", match, kind, strings.Join(secretKinds(), ", "))
+			return ""
+		}
+		val, err := r(ref)
+		if err != nil {
+			firstErr = fmt.Errorf("%s: %w", match, err)
+			return ""
+		}
+		cache[match] = val
+		return val
+	})
+	return out, firstErr
+}
+
+func secretKinds() []string {
+	kinds := make([]string, 0, len(secretResolvers))
+	for k := range secretResolvers {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
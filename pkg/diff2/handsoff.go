@@ -120,8 +120,20 @@ func handsoff(
 		punct = "."
 	}
 
+	// Leave the records that a partial push (--types, --labels) did not
+	// select as they are: drop them from desired, and keep the existing ones.
+	unmanagedConfigs, inverted := splitInverted(unmanagedConfigs)
+	selected, unselected := existing, models.Records(nil)
+	if len(inverted) != 0 {
+		selected, unselected = partitionRecords(inverted, existing)
+		desired, _ = partitionRecords(inverted, desired)
+	}
+	if len(unselected) != 0 {
+		msgs = append(msgs, fmt.Sprintf("%d records not changed because they are not selected by --types/--labels.", len(unselected)))
+	}
+
 	// Process IGNORE*() and NO_PURGE features:
-	ignorable, foreign, err := processIgnoreAndNoPurge(domain, existing, desired, absences, unmanagedConfigs, noPurge)
+	ignorable, foreign, err := processIgnoreAndNoPurge(domain, selected, desired, absences, unmanagedConfigs, noPurge)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -150,9 +162,36 @@ func handsoff(
 	// Add the ignored/foreign items to the desired list so they are not deleted:
 	desired = append(desired, ignorable...)
 	desired = append(desired, foreign...)
+	desired = append(desired, unselected...)
 	return desired, msgs, nil
 }
 
+// splitInverted separates the (compiled) UnmanagedConfigs of a partial push
+// (Invert is set) from the IGNORE*() ones.
+func splitInverted(configs []*models.UnmanagedConfig) (ignores, inverted []*models.UnmanagedConfig) {
+	for _, c := range configs {
+		if c.Invert {
+			inverted = append(inverted, c)
+		} else {
+			ignores = append(ignores, c)
+		}
+	}
+	return ignores, inverted
+}
+
+// partitionRecords returns the recs that do not match any of the
+// (compiled) uconfigs, and those that do.
+func partitionRecords(uconfigs []*models.UnmanagedConfig, recs models.Records) (unmatched, matched models.Records) {
+	for _, rec := range recs {
+		if matchAny(uconfigs, rec) {
+			matched = append(matched, rec)
+		} else {
+			unmatched = append(unmatched, rec)
+		}
+	}
+	return unmatched, matched
+}
+
 // reportSkips reports records being skipped, if !full only the first
 // printer.MaxReport are output.
 func reportSkips(recs models.Records, full bool) []string {
@@ -251,13 +290,25 @@ func compileUnmanagedConfigs(configs []*models.UnmanagedConfig) error {
 	return nil
 }
 
+// UnmanagedMatcher returns a function that reports whether a record
+// matches any of the IGNORE*() configs.
+func UnmanagedMatcher(configs []*models.UnmanagedConfig) (func(*models.RecordConfig) bool, error) {
+	if err := compileUnmanagedConfigs(configs); err != nil {
+		return nil, err
+	}
+	return func(rec *models.RecordConfig) bool {
+		return matchAny(configs, rec)
+	}, nil
+}
+
 // matchAny returns true if rec matches any of the uconfigs.
 func matchAny(uconfigs []*models.UnmanagedConfig, rec *models.RecordConfig) bool {
 	// fmt.Printf("DEBUG: matchAny(%s, %q, %q, %q)\n", models.DebugUnmanagedConfig(uconfigs), rec.NameFQDN, rec.Type, rec.GetTargetField())
 	for _, uc := range uconfigs {
-		if matchLabel(uc.LabelGlob, rec.GetLabel()) &&
+		isMatch := matchLabel(uc.LabelGlob, rec.GetLabel()) &&
 			matchType(uc.RTypeMap, rec.Type) &&
-			matchTarget(uc.TargetGlob, rec.GetTargetField()) {
+			matchTarget(uc.TargetGlob, rec.GetTargetField())
+		if isMatch != uc.Invert {
 			return true
 		}
 	}
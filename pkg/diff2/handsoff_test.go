@@ -237,3 +237,45 @@ _2222222222222222.cr CNAME _333333.nnn.acm-validations.aws.
 FOREIGN:
 	`)
 }
+
+func Test_handsoff_inverted(t *testing.T) {
+	existing, err := parseZoneContents(`
+_acme-challenge IN TXT "old"
+www IN A 1.1.1.1
+mail IN A 2.2.2.2
+foo IN A 4.4.4.4
+`, "f.com", "no_file_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dnsconfig, err := js.ExecuteJavascriptString([]byte(`
+D("f.com", "none",
+	TXT("_acme-challenge", "new"),
+	A("www", "9.9.9.9"),
+	A("ftp", "3.3.3.3"),
+	IGNORE("foo"),
+{})
+`), false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := dnsconfig.FindDomain("f.com")
+	for _, rec := range dc.Records {
+		rec.SetLabel(rec.GetLabel(), "f.com")
+	}
+
+	// As set by push --types TXT --labels '_acme*'.
+	configs := append(dc.Unmanaged, &models.UnmanagedConfig{LabelPattern: "_acme*", RTypePattern: "TXT", Invert: true})
+	desired, msgs, err := handsoff("f.com", existing, dc.Records, nil, configs, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the selected TXT record changes; the pending A changes are not
+	// made, and the unselected records are not deleted even with NO_PURGE.
+	testifyrequire.Equal(t, `_acme-challenge TXT "new"
+www A 1.1.1.1
+mail A 2.2.2.2
+foo A 4.4.4.4
+`, showRecs(desired))
+	testifyrequire.Contains(t, strings.Join(msgs, "\n"), "3 records not changed because they are not selected by --types/--labels.")
+}
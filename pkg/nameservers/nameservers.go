@@ -2,6 +2,7 @@
 package nameservers
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -19,6 +20,12 @@ func DetermineNameservers(dc *models.DomainConfig) ([]*models.Nameserver, error)
 
 // DetermineNameserversForProviders is like DetermineNameservers, for a subset of providers.
 func DetermineNameserversForProviders(dc *models.DomainConfig, providers []*models.DNSProviderInstance, silent bool) ([]*models.Nameserver, error) {
+	return DetermineNameserversForProvidersContext(context.Background(), dc, providers, silent)
+}
+
+// DetermineNameserversForProvidersContext is like
+// DetermineNameserversForProviders but gives up when ctx is done.
+func DetermineNameserversForProvidersContext(ctx context.Context, dc *models.DomainConfig, providers []*models.DNSProviderInstance, silent bool) ([]*models.Nameserver, error) {
 	// start with the nameservers that have been explicitly added:
 	ns := dc.Nameservers
 
@@ -31,7 +38,7 @@ func DetermineNameserversForProviders(dc *models.DomainConfig, providers []*mode
 			fmt.Printf("----- Getting nameservers from: %s\n", dnsProvider.Name)
 		}
 
-		nss, err := dnsProvider.Driver.GetNameservers(dc.Name)
+		nss, err := models.GetNameserversContext(ctx, dnsProvider.Driver, dc.Name)
 		if err != nil {
 			return nil, fmt.Errorf("error while getting Nameservers for zone=%q with provider=%q: %w", dc.Name, dnsProvider.Name, err)
 		}
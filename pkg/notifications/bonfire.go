@@ -7,11 +7,11 @@ import (
 )
 
 func init() {
-	initers = append(initers, func(cfg map[string]string) Notifier {
+	initers = append(initers, func(cfg map[string]string) (Notifier, error) {
 		if url, ok := cfg["bonfire_url"]; ok {
-			return bonfireNotifier(url)
+			return configure(cfg, "bonfire", bonfireNotifier(url), markdownDigest)
 		}
-		return nil
+		return nil, nil
 	})
 }
 
@@ -19,18 +19,19 @@ func init() {
 type bonfireNotifier string
 
 func (b bonfireNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
-	var payload string
 	if preview {
-		payload = fmt.Sprintf(`**Preview: %s[%s] -** %s`, domain, provider, msg)
+		return b.send(fmt.Sprintf(`**Preview: %s[%s] -** %s`, domain, provider, msg))
 	} else if err != nil {
-		payload = fmt.Sprintf(`**ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err)
-	} else {
-		payload = fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
+		return b.send(fmt.Sprintf(`**ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err))
 	}
+	return b.send(fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg))
+}
+
+func (b bonfireNotifier) send(payload string) error {
 	// chat doesn't markdownify multiline messages. Split in two so the first line can have markdown
 	parts := strings.SplitN(payload, "\n", 2)
 	for _, p := range parts {
-		_, err = http.Post(string(b), "text/markdown", strings.NewReader(p))
+		_, err := http.Post(string(b), "text/markdown", strings.NewReader(p))
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,203 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+// digestMaxChanges is the number of changes listed for each zone and
+// provider in a digest.  The rest are only counted.
+const digestMaxChanges = 10
+
+// textSender is implemented by the notifiers that send text messages.
+type textSender interface {
+	Notifier
+	send(text string) error
+}
+
+type digestEntry struct {
+	domain, provider, msg string
+	err                   error
+	preview               bool
+}
+
+// digestNotifier collects the notifications of a run and sends them as a
+// single summary when the run is done.
+type digestNotifier struct {
+	n       textSender
+	style   digestStyle
+	tmpl    *template.Template // Formats the digest instead of formatDigest, if set.
+	entries []digestEntry
+}
+
+func (d *digestNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	d.entries = append(d.entries, digestEntry{domain: domain, provider: provider, msg: msg, err: err, preview: preview})
+	return nil
+}
+
+func (d *digestNotifier) Done() {
+	if len(d.entries) == 0 {
+		return
+	}
+	text, err := d.format()
+	if err == nil {
+		err = d.n.send(text)
+	}
+	if err != nil {
+		printer.Warnf("Error sending notification digest: %s\n", err)
+	}
+	d.entries = nil
+}
+
+// format returns the digest: the summary, or the template executed with
+// DigestData.
+func (d *digestNotifier) format() (string, error) {
+	summary := formatDigest(d.entries, d.style)
+	if d.tmpl == nil {
+		return summary, nil
+	}
+	data := DigestData{Title: digestTitle(d.entries), Summary: summary}
+	for _, e := range d.entries {
+		td := TemplateData{Domain: e.domain, Provider: e.provider, Message: e.msg, Preview: e.preview}
+		if e.err != nil {
+			td.Error = e.err.Error()
+		}
+		data.Corrections = append(data.Corrections, td)
+		data.Preview = data.Preview || e.preview
+	}
+	var b bytes.Buffer
+	if err := d.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("notifications: %s_template: %w", d.tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// digestStyle adapts the digest to the markup of a backend.
+type digestStyle struct {
+	bold   func(string) string
+	escape func(string) string
+	alert  func(string) string // Highlights the errors. Defaults to bold.
+}
+
+func (s digestStyle) highlight(text string) string {
+	if s.alert != nil {
+		return s.alert(text)
+	}
+	return s.bold(text)
+}
+
+var (
+	plainDigest    = digestStyle{bold: strings.ToUpper, escape: func(s string) string { return s }}
+	markdownDigest = digestStyle{bold: func(s string) string { return "**" + s + "**" }, escape: func(s string) string { return s }}
+	htmlDigest     = digestStyle{bold: func(s string) string { return "<b>" + s + "</b>" }, escape: html.EscapeString}
+)
+
+// digestChange matches the lines of a correction message that describe a
+// change: "+ CREATE ...", "± MODIFY ...", "- DELETE ...", etc.
+var digestChange = regexp.MustCompile(`^\s*(?:[-+±] )?(CREATE|MODIFY|MODIFY-TTL|CHANGE|DELETE)\b`)
+
+type digestGroup struct {
+	domain, provider          string
+	creates, changes, deletes int
+	others                    int
+	lines                     []string
+}
+
+// digestTitle returns the first line of a digest, for example "DNSControl
+// push: 16 corrections for 2 zones, 1 failed".
+func digestTitle(entries []digestEntry) string {
+	action := "push"
+	zones := map[string]bool{}
+	failed := 0
+	for _, e := range entries {
+		if e.preview {
+			action = "preview"
+		}
+		if e.err != nil {
+			failed++
+		}
+		zones[e.domain] = true
+	}
+	title := fmt.Sprintf("DNSControl %s: %d corrections for %d zones", action, len(entries), len(zones))
+	if failed > 0 {
+		title += fmt.Sprintf(", %d failed", failed)
+	}
+	return title
+}
+
+// formatDigest summarizes entries: first the corrections that failed, then
+// for each zone and provider the number of creates, changes and deletes and
+// the first few of them.
+func formatDigest(entries []digestEntry, style digestStyle) string {
+	var groups []*digestGroup
+	byKey := map[[2]string]*digestGroup{}
+	var failed []digestEntry
+
+	for _, e := range entries {
+		if e.err != nil {
+			failed = append(failed, e)
+		}
+		k := [2]string{e.domain, e.provider}
+		g, ok := byKey[k]
+		if !ok {
+			g = &digestGroup{domain: e.domain, provider: e.provider}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+
+		counted := false
+		for _, line := range strings.Split(e.msg, "\n") {
+			m := digestChange.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			counted = true
+			switch m[1] {
+			case "CREATE":
+				g.creates++
+			case "DELETE":
+				g.deletes++
+			default:
+				g.changes++
+			}
+			g.lines = append(g.lines, strings.TrimSpace(line))
+		}
+		if !counted {
+			g.others++
+			g.lines = append(g.lines, strings.TrimSpace(strings.SplitN(e.msg, "\n", 2)[0]))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(style.bold(style.escape(digestTitle(entries))) + "\n")
+
+	if len(failed) > 0 {
+		b.WriteString("\n" + style.highlight("Errors:") + "\n")
+		for _, e := range failed {
+			first := strings.TrimSpace(strings.SplitN(e.msg, "\n", 2)[0])
+			fmt.Fprintf(&b, "%s\n    Error: %s\n", style.escape(fmt.Sprintf("%s[%s]: %s", e.domain, e.provider, first)), style.escape(e.err.Error()))
+		}
+	}
+
+	for _, g := range groups {
+		counts := fmt.Sprintf("%d created, %d changed, %d deleted", g.creates, g.changes, g.deletes)
+		if g.others > 0 {
+			counts += fmt.Sprintf(", %d other", g.others)
+		}
+		fmt.Fprintf(&b, "\n%s: %s\n", style.bold(style.escape(fmt.Sprintf("%s[%s]", g.domain, g.provider))), counts)
+		for i, line := range g.lines {
+			if i == digestMaxChanges {
+				fmt.Fprintf(&b, "    ... and %d more\n", len(g.lines)-digestMaxChanges)
+				break
+			}
+			b.WriteString("    " + style.escape(line) + "\n")
+		}
+	}
+	return b.String()
+}
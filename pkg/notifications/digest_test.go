@@ -0,0 +1,93 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeSender struct {
+	sent []string
+}
+
+func (f *fakeSender) Notify(domain, provider, msg string, err error, preview bool) error {
+	return nil
+}
+func (f *fakeSender) Done() {}
+func (f *fakeSender) send(text string) error {
+	f.sent = append(f.sent, text)
+	return nil
+}
+
+func Test_digestNotifier(t *testing.T) {
+	f := &fakeSender{}
+	n, _ := configure(map[string]string{"fake_digest": "true"}, "fake", f, markdownDigest)
+
+	n.Notify("example.com", "r53", "+ CREATE a.example.com A 1.2.3.4 ttl=300", nil, false)
+	n.Notify("example.com", "r53", "± MODIFY b.example.com A (1.2.3.4 ttl=300) -> (1.2.3.5 ttl=300)\n- DELETE c.example.com A 1.2.3.6 ttl=300", nil, false)
+	n.Notify("example.com", "r53", "- DELETE d.example.com TXT \"x\" ttl=300", errors.New("boom"), false)
+	for i := range 12 {
+		n.Notify("example.net", "gcloud", fmt.Sprintf("+ CREATE h%d.example.net A 10.0.0.%d ttl=300", i, i), nil, false)
+	}
+	n.Notify("example.net", "gcloud", "Enable DNSSEC", nil, false)
+	if len(f.sent) != 0 {
+		t.Fatalf("sent before Done: %v", f.sent)
+	}
+	n.Done()
+	if len(f.sent) != 1 {
+		t.Fatalf("got %d messages, want 1", len(f.sent))
+	}
+
+	want := `**DNSControl push: 16 corrections for 2 zones, 1 failed**
+
+**Errors:**
+example.com[r53]: - DELETE d.example.com TXT "x" ttl=300
+    Error: boom
+
+**example.com[r53]**: 1 created, 1 changed, 2 deleted
+    + CREATE a.example.com A 1.2.3.4 ttl=300
+    ± MODIFY b.example.com A (1.2.3.4 ttl=300) -> (1.2.3.5 ttl=300)
+    - DELETE c.example.com A 1.2.3.6 ttl=300
+    - DELETE d.example.com TXT "x" ttl=300
+
+**example.net[gcloud]**: 12 created, 0 changed, 0 deleted, 1 other
+    + CREATE h0.example.net A 10.0.0.0 ttl=300
+    + CREATE h1.example.net A 10.0.0.1 ttl=300
+    + CREATE h2.example.net A 10.0.0.2 ttl=300
+    + CREATE h3.example.net A 10.0.0.3 ttl=300
+    + CREATE h4.example.net A 10.0.0.4 ttl=300
+    + CREATE h5.example.net A 10.0.0.5 ttl=300
+    + CREATE h6.example.net A 10.0.0.6 ttl=300
+    + CREATE h7.example.net A 10.0.0.7 ttl=300
+    + CREATE h8.example.net A 10.0.0.8 ttl=300
+    + CREATE h9.example.net A 10.0.0.9 ttl=300
+    ... and 3 more
+`
+	if f.sent[0] != want {
+		t.Errorf("digest:\n%s\nwant:\n%s", f.sent[0], want)
+	}
+
+	// Nothing to say, nothing sent.
+	n.Done()
+	if len(f.sent) != 1 {
+		t.Errorf("empty digest was sent")
+	}
+}
+
+func Test_configureDigest(t *testing.T) {
+	f := &fakeSender{}
+	if n, _ := configure(map[string]string{}, "fake", f, plainDigest); n != Notifier(f) {
+		t.Errorf("digest enabled without fake_digest")
+	}
+	if n, _ := configure(map[string]string{"slack_digest": "true"}, "fake", f, plainDigest); n != Notifier(f) {
+		t.Errorf("digest enabled by another backend's setting")
+	}
+}
+
+func Test_formatDigestHTML(t *testing.T) {
+	got := formatDigest([]digestEntry{{domain: "example.com", provider: "p", msg: "+ CREATE example.com TXT \"<x>\"", preview: true}}, htmlDigest)
+	if !strings.HasPrefix(got, "<b>DNSControl preview: 1 corrections for 1 zones</b>\n") || !strings.Contains(got, "&#34;&lt;x&gt;&#34;") {
+		t.Errorf("formatDigest:\n%s", got)
+	}
+}
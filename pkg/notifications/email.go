@@ -0,0 +1,200 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+func init() {
+	initers = append(initers, func(cfg map[string]string) (Notifier, error) {
+		host, ok := cfg["email_smtp_host"]
+		if !ok {
+			return nil, nil
+		}
+		notifier := &emailNotifier{
+			Host:     host,
+			Port:     cfg["email_smtp_port"],
+			TLS:      cfg["email_tls"],
+			Username: cfg["email_username"],
+			Password: cfg["email_password"],
+			From:     cfg["email_from"],
+			Subject:  cfg["email_subject"],
+		}
+		if notifier.From == "" || cfg["email_to"] == "" {
+			return nil, fmt.Errorf("notifications: email_from and email_to are required")
+		}
+		from, err := mail.ParseAddress(notifier.From)
+		if err != nil {
+			return nil, fmt.Errorf("notifications: invalid email_from %q: %w", notifier.From, err)
+		}
+		notifier.envelopeFrom = from.Address
+		to, err := mail.ParseAddressList(cfg["email_to"])
+		if err != nil {
+			return nil, fmt.Errorf("notifications: invalid email_to %q: %w", cfg["email_to"], err)
+		}
+		for _, a := range to {
+			notifier.To = append(notifier.To, a.String())
+			notifier.envelopeTo = append(notifier.envelopeTo, a.Address)
+		}
+		switch notifier.TLS {
+		case "":
+			notifier.TLS = "starttls"
+		case "starttls", "implicit", "none":
+		default:
+			return nil, fmt.Errorf("notifications: invalid email_tls %q: must be starttls, implicit or none", notifier.TLS)
+		}
+		if notifier.Port == "" {
+			notifier.Port = "587"
+			if notifier.TLS == "implicit" {
+				notifier.Port = "465"
+			}
+		}
+		return configure(cfg, "email", notifier, digestStyle{})
+	})
+}
+
+// emailNotifier emails a digest of the run's notifications when the run is
+// done.
+type emailNotifier struct {
+	Host     string
+	Port     string
+	TLS      string // "starttls", "implicit" or "none"
+	Username string
+	Password string
+	From     string
+	To       []string
+	Subject  string // Defaults to the title of the digest.
+
+	envelopeFrom string
+	envelopeTo   []string
+	tlsConfig    *tls.Config // For tests.
+	entries      []digestEntry
+}
+
+func (e *emailNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	e.entries = append(e.entries, digestEntry{domain: domain, provider: provider, msg: msg, err: err, preview: preview})
+	return nil
+}
+
+func (e *emailNotifier) Done() {
+	if len(e.entries) == 0 {
+		return
+	}
+	if err := e.deliver(e.message(time.Now())); err != nil {
+		printer.Warnf("Error sending notification email: %s\n", err)
+	}
+	e.entries = nil
+}
+
+var (
+	emailTextDigest = digestStyle{bold: func(s string) string { return s }, escape: func(s string) string { return s }}
+	emailHTMLDigest = digestStyle{
+		bold:   func(s string) string { return "<b>" + s + "</b>" },
+		escape: html.EscapeString,
+		alert:  func(s string) string { return `<b style="color:#c00">` + s + "</b>" },
+	}
+)
+
+// message returns the email, with a plain text and an HTML part.
+func (e *emailNotifier) message(now time.Time) []byte {
+	subject := e.Subject
+	if subject == "" {
+		subject = digestTitle(e.entries)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	writePart := func(contentType, content string) {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qw := quotedprintable.NewWriter(w)
+		qw.Write([]byte(content))
+		qw.Close()
+	}
+	writePart("text/plain", formatDigest(e.entries, emailTextDigest))
+	writePart("text/html", `<html><body><pre style="font-family:monospace">`+formatDigest(e.entries, emailHTMLDigest)+"</pre></body></html>\n")
+	mw.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n", mw.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+// deliver sends msg over SMTP.
+func (e *emailNotifier) deliver(msg []byte) error {
+	addr := net.JoinHostPort(e.Host, e.Port)
+	tlsConfig := e.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: e.Host}
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if e.TLS == "implicit" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.TLS == "starttls" {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+	}
+	if e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.envelopeFrom); err != nil {
+		return err
+	}
+	for _, to := range e.envelopeTo {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,234 @@
+package notifications
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts one message per
+// connection.
+type smtpStandIn struct {
+	ln       net.Listener
+	tls      *tls.Config // For STARTTLS, if set.
+	auth     string      // The decoded AUTH PLAIN response.
+	from     string
+	to       []string
+	data     string
+	startTLS bool
+	done     chan struct{}
+}
+
+func newSMTPStandIn(t *testing.T, ln net.Listener, tlsConfig *tls.Config) *smtpStandIn {
+	s := &smtpStandIn{ln: ln, tls: tlsConfig, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO":
+			reply("250-localhost")
+			if s.tls != nil && !s.startTLS {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case cmd == "STARTTLS":
+			reply("220 go ahead")
+			tconn := tls.Server(conn, s.tls)
+			if tconn.Handshake() != nil {
+				return
+			}
+			conn, r, s.startTLS = tconn, bufio.NewReader(tconn), true
+			reply = func(line string) { io.WriteString(conn, line+"\r\n") }
+		case cmd == "AUTH":
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			s.auth = string(b)
+			reply("235 ok")
+		case strings.HasPrefix(line, "MAIL FROM:"):
+			s.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 ok")
+		case strings.HasPrefix(line, "RCPT TO:"):
+			s.to = append(s.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = b.String()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("500 what?")
+		}
+	}
+}
+
+// testTLS returns a server and client TLS config for 127.0.0.1.
+func testTLS(t *testing.T) (server, client *tls.Config) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	server = &tls.Config{Certificates: srv.TLS.Certificates}
+	client = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	client.ServerName = "127.0.0.1"
+	return server, client
+}
+
+func newTestEmailNotifier(t *testing.T, addr net.Addr, mode string, clientTLS *tls.Config) *emailNotifier {
+	host, port, _ := net.SplitHostPort(addr.String())
+	n, err := Init(map[string]string{
+		"email_smtp_host": host,
+		"email_smtp_port": port,
+		"email_tls":       mode,
+		"email_username":  "dns",
+		"email_password":  "pw",
+		"email_from":      "DNSControl <dns@example.com>",
+		"email_to":        "change-control@example.com, Ops <ops@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := n.(multiNotifier)[0].(*emailNotifier)
+	e.tlsConfig = clientTLS
+	return e
+}
+
+func Test_emailNotifier(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+
+	for _, mode := range []string{"none", "starttls", "implicit"} {
+		t.Run(mode, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s *smtpStandIn
+			switch mode {
+			case "none":
+				s = newSMTPStandIn(t, ln, nil)
+			case "starttls":
+				s = newSMTPStandIn(t, ln, serverTLS)
+			case "implicit":
+				s = newSMTPStandIn(t, tls.NewListener(ln, serverTLS), nil)
+			}
+
+			e := newTestEmailNotifier(t, ln.Addr(), mode, clientTLS)
+			e.Notify("example.com", "r53", "+ CREATE www.example.com A 1.2.3.4 ttl=300", nil, false)
+			e.Notify("example.com", "r53", "- DELETE old.example.com TXT \"<x>\" ttl=300", errors.New("boom"), false)
+			if err := e.deliver(e.message(time.Now())); err != nil {
+				t.Fatal(err)
+			}
+			<-s.done
+
+			if mode == "starttls" && !s.startTLS {
+				t.Error("STARTTLS was not used")
+			}
+			if s.auth != "\x00dns\x00pw" {
+				t.Errorf("auth: got %q", s.auth)
+			}
+			if s.from != "dns@example.com" || strings.Join(s.to, " ") != "change-control@example.com ops@example.com" {
+				t.Errorf("envelope: from %q to %q", s.from, s.to)
+			}
+			checkEmail(t, s.data)
+		})
+	}
+}
+
+func checkEmail(t *testing.T, data string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "DNSControl push: 2 corrections for 1 zones, 1 failed" {
+		t.Errorf("subject: %q", subject)
+	}
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("content type: %q", mediaType)
+	}
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		b, _ := io.ReadAll(p) // Decodes quoted-printable.
+		parts[ct] = strings.ReplaceAll(string(b), "\r\n", "\n")
+	}
+	if !strings.Contains(parts["text/plain"], "Errors:\nexample.com[r53]: - DELETE old.example.com TXT \"<x>\" ttl=300\n    Error: boom\n") {
+		t.Errorf("text part:\n%s", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], `<b style="color:#c00">Errors:</b>`) || !strings.Contains(parts["text/html"], "&#34;&lt;x&gt;&#34;") {
+		t.Errorf("html part:\n%s", parts["text/html"])
+	}
+}
+
+func Test_emailNotifierConfig(t *testing.T) {
+	tests := []map[string]string{
+		{"email_smtp_host": "smtp.example.com", "email_to": "a@example.com"},
+		{"email_smtp_host": "smtp.example.com", "email_from": "a@example.com"},
+		{"email_smtp_host": "smtp.example.com", "email_from": "a@example.com", "email_to": "b@example.com", "email_tls": "ssl"},
+		{"email_smtp_host": "smtp.example.com", "email_from": "not an address", "email_to": "b@example.com"},
+		{"email_smtp_host": "smtp.example.com", "email_from": "a@example.com", "email_to": "b@example.com", "email_digest": "true"},
+	}
+	for _, cfg := range tests {
+		if _, err := Init(cfg); err == nil {
+			t.Errorf("%v: expected an error", cfg)
+		}
+	}
+
+	n, err := Init(map[string]string{"email_smtp_host": "smtp.example.com", "email_from": "a@example.com", "email_to": "b@example.com", "email_tls": "implicit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := n.(multiNotifier)[0].(*emailNotifier); e.Port != "465" {
+		t.Errorf("implicit TLS port: got %q", e.Port)
+	}
+}
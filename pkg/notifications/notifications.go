@@ -1,6 +1,11 @@
 package notifications
 
-import "regexp"
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
 
 // Notifier is a type that can send a notification
 type Notifier interface {
@@ -14,22 +19,52 @@ type Notifier interface {
 }
 
 // new notification types should add themselves to this array
-var initers = []func(map[string]string) Notifier{}
+var initers = []func(map[string]string) (Notifier, error){}
 
 // matches ansi color codes
 var ansiColorRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
 // Init will take the given config map (from creds.json notifications key) and create a single Notifier with
 // all notifications it has full config for.
-func Init(config map[string]string) Notifier {
+//
+// A backend can be configured more than once by adding "@name" to its keys:
+// "slack_url@dba" and "slack_zones@dba" configure a second Slack
+// notifier, independent of "slack_url".
+func Init(config map[string]string) (Notifier, error) {
 	notifiers := multiNotifier{}
-	for _, i := range initers {
-		n := i(config)
-		if n != nil {
-			notifiers = append(notifiers, n)
+	for _, cfg := range splitInstances(config) {
+		for _, i := range initers {
+			n, err := i(cfg)
+			if err != nil {
+				return nil, err
+			}
+			if n != nil {
+				notifiers = append(notifiers, n)
+			}
 		}
 	}
-	return notifiers
+	return notifiers, nil
+}
+
+// splitInstances splits config into one map per "@name" suffix, sorted by
+// name.  Keys without a suffix are in the first map.
+func splitInstances(config map[string]string) []map[string]string {
+	byName := map[string]map[string]string{"": {}}
+	for k, v := range config {
+		name := ""
+		if i := strings.LastIndex(k, "@"); i >= 0 {
+			k, name = k[:i], k[i+1:]
+		}
+		if byName[name] == nil {
+			byName[name] = map[string]string{}
+		}
+		byName[name][k] = v
+	}
+	var cfgs []map[string]string
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		cfgs = append(cfgs, byName[name])
+	}
+	return cfgs
 }
 
 type multiNotifier []Notifier
@@ -0,0 +1,143 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/zoneglob"
+)
+
+// configure applies the settings that all backends share to the backend
+// n, named name.  Each setting is "<name>_<setting>" in the notifications
+// section of creds.json:
+//
+//	_template      A text/template for the message (see TemplateData), or
+//	               for the digest if _digest is set (see DigestData).
+//	_digest        "true" to send one summary per run.
+//	_only_errors   "true" to only send the corrections that failed.
+//	_only_push     "true" to not send anything during preview.
+//	_only_deletes  "true" to only send the corrections that delete records.
+//	_zones         Only send the corrections of zones that match one of
+//	               these comma-separated globs (for example "*.example.com").
+//	               See zoneglob for the syntax.
+//
+// Templates and digests are only supported by backends that send text.
+func configure(cfg map[string]string, name string, n Notifier, style digestStyle) (Notifier, error) {
+	var tmpl *template.Template
+	if t, ok := cfg[name+"_template"]; ok {
+		if _, ok := n.(textSender); !ok {
+			return nil, fmt.Errorf("notifications: %s_template is not supported", name)
+		}
+		var err error
+		if tmpl, err = template.New(name).Parse(t); err != nil {
+			return nil, fmt.Errorf("notifications: %s_template: %w", name, err)
+		}
+	}
+
+	switch ts, _ := n.(textSender); {
+	case cfg[name+"_digest"] == "true":
+		if ts == nil {
+			return nil, fmt.Errorf("notifications: %s_digest is not supported", name)
+		}
+		n = &digestNotifier{n: ts, style: style, tmpl: tmpl}
+	case tmpl != nil:
+		n = &templateNotifier{textSender: ts, tmpl: tmpl}
+	}
+
+	f := notifyFilter{
+		onlyErrors:  cfg[name+"_only_errors"] == "true",
+		onlyPush:    cfg[name+"_only_push"] == "true",
+		onlyDeletes: cfg[name+"_only_deletes"] == "true",
+	}
+	zones, err := zoneglob.CompileList(cfg[name+"_zones"])
+	if err != nil {
+		return nil, fmt.Errorf("notifications: %s_zones: %w", name, err)
+	}
+	f.zones = zones
+	if f.onlyErrors || f.onlyPush || f.onlyDeletes || len(f.zones) > 0 {
+		n = &filteredNotifier{Notifier: n, filter: f}
+	}
+	return n, nil
+}
+
+// TemplateData is the data that a notification template is executed with.
+type TemplateData struct {
+	Domain   string
+	Provider string
+	Message  string
+	Error    string // Empty if the correction succeeded.
+	Preview  bool
+}
+
+// DigestData is the data that a notification template is executed with
+// when _digest is set.
+type DigestData struct {
+	Title       string         // For example "DNSControl push: 16 corrections for 2 zones, 1 failed".
+	Summary     string         // The digest that is sent if there is no template.
+	Corrections []TemplateData // Every correction of the run.
+	Preview     bool
+}
+
+// templateNotifier formats each message with a user-defined template.
+type templateNotifier struct {
+	textSender
+	tmpl *template.Template
+}
+
+func (t *templateNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	data := TemplateData{Domain: domain, Provider: provider, Message: msg, Preview: preview}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	var b bytes.Buffer
+	if terr := t.tmpl.Execute(&b, data); terr != nil {
+		return fmt.Errorf("notifications: %s_template: %w", t.tmpl.Name(), terr)
+	}
+	return t.send(b.String())
+}
+
+// notifyFilter selects the notifications that a backend receives.
+type notifyFilter struct {
+	onlyErrors  bool
+	onlyPush    bool
+	onlyDeletes bool
+	zones       []zoneglob.Matcher
+}
+
+func (f notifyFilter) match(domain, msg string, err error, preview bool) bool {
+	if f.onlyErrors && err == nil {
+		return false
+	}
+	if f.onlyPush && preview {
+		return false
+	}
+	if f.onlyDeletes && !hasDelete(msg) {
+		return false
+	}
+	return len(f.zones) == 0 || zoneglob.MatchAny(f.zones, domain)
+}
+
+// hasDelete returns true if a correction message deletes a record.
+func hasDelete(msg string) bool {
+	for _, line := range strings.Split(msg, "\n") {
+		if m := digestChange.FindStringSubmatch(line); m != nil && m[1] == "DELETE" {
+			return true
+		}
+	}
+	return false
+}
+
+// filteredNotifier passes the notifications that match filter to Notifier.
+type filteredNotifier struct {
+	Notifier
+	filter notifyFilter
+}
+
+func (f *filteredNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	if !f.filter.match(domain, msg, err, preview) {
+		return nil
+	}
+	return f.Notifier.Notify(domain, provider, msg, err, preview)
+}
@@ -0,0 +1,134 @@
+package notifications
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_configureFilters(t *testing.T) {
+	type note struct {
+		domain, msg string
+		err         error
+		preview     bool
+	}
+	notes := []note{
+		{"example.com", "+ CREATE www.example.com A 1.2.3.4", nil, true},
+		{"example.com", "- DELETE old.example.com A 1.2.3.4", nil, false},
+		{"db.example.com", "± MODIFY x.db.example.com A 1.2.3.4", errors.New("boom"), false},
+		{"example.net", "+ CREATE www.example.net A 1.2.3.4\n- DELETE old.example.net A 1.2.3.4", nil, false},
+	}
+
+	tests := []struct {
+		name string
+		cfg  map[string]string
+		want []string // The domains notified.
+	}{
+		{"none", map[string]string{}, []string{"example.com", "example.com", "db.example.com", "example.net"}},
+		{"errors", map[string]string{"fake_only_errors": "true"}, []string{"db.example.com"}},
+		{"push", map[string]string{"fake_only_push": "true"}, []string{"example.com", "db.example.com", "example.net"}},
+		{"deletes", map[string]string{"fake_only_deletes": "true"}, []string{"example.com", "example.net"}},
+		{"zones", map[string]string{"fake_zones": "*.example.com, EXAMPLE.NET"}, []string{"db.example.com", "example.net"}},
+		{"zones with dots", map[string]string{"fake_zones": "*.com"}, []string{"example.com", "example.com", "db.example.com"}},
+		{"combined", map[string]string{"fake_only_push": "true", "fake_zones": "example.com"}, []string{"example.com"}},
+		{"other backend", map[string]string{"slack_only_errors": "true"}, []string{"example.com", "example.com", "db.example.com", "example.net"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			f := &recorder{notify: func(domain string) { got = append(got, domain) }}
+			n, err := configure(tt.cfg, "fake", f, plainDigest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, x := range notes {
+				n.Notify(x.domain, "p", x.msg, x.err, x.preview)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_configureTemplate(t *testing.T) {
+	f := &fakeSender{}
+	n, err := configure(map[string]string{
+		"fake_template": `{{if .Error}}FAILED{{else if .Preview}}PREVIEW{{else}}OK{{end}} {{.Domain}}/{{.Provider}}: {{.Message}}{{with .Error}} ({{.}}){{end}}`,
+	}, "fake", f, plainDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify("example.com", "r53", "+ CREATE www", nil, true)
+	n.Notify("example.com", "r53", "+ CREATE www", errors.New("boom"), false)
+	want := []string{
+		"PREVIEW example.com/r53: + CREATE www",
+		"FAILED example.com/r53: + CREATE www (boom)",
+	}
+	if !reflect.DeepEqual(f.sent, want) {
+		t.Errorf("got %q, want %q", f.sent, want)
+	}
+}
+
+func Test_configureTemplateDigest(t *testing.T) {
+	f := &fakeSender{}
+	n, err := configure(map[string]string{
+		"fake_digest":   "true",
+		"fake_template": `{{.Title}}{{range .Corrections}}|{{.Domain}}{{with .Error}} ({{.}}){{end}}{{end}}`,
+	}, "fake", f, plainDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify("example.com", "r53", "+ CREATE www", nil, false)
+	n.Notify("example.net", "r53", "+ CREATE www", errors.New("boom"), false)
+	n.Done()
+	want := []string{"DNSControl push: 2 corrections for 2 zones, 1 failed|example.com|example.net (boom)"}
+	if !reflect.DeepEqual(f.sent, want) {
+		t.Errorf("got %q, want %q", f.sent, want)
+	}
+}
+
+func Test_configureErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  map[string]string
+		n    Notifier
+	}{
+		{"bad template", map[string]string{"fake_template": "{{.Domain"}, &fakeSender{}},
+		{"bad glob", map[string]string{"fake_zones": "[example.com"}, &fakeSender{}},
+		{"template not supported", map[string]string{"fake_template": "x"}, &recorder{}},
+		{"digest not supported", map[string]string{"fake_digest": "true"}, &recorder{}},
+	}
+	for _, tt := range tests {
+		if _, err := configure(tt.cfg, "fake", tt.n, plainDigest); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func Test_splitInstances(t *testing.T) {
+	got := splitInstances(map[string]string{
+		"slack_url":       "https://a",
+		"slack_url@dba":   "https://b",
+		"slack_zones@dba": "db.example.com",
+		"teams_url":       "https://c",
+	})
+	want := []map[string]string{
+		{"slack_url": "https://a", "teams_url": "https://c"},
+		{"slack_url": "https://b", "slack_zones": "db.example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// recorder is a Notifier that does not send text.
+type recorder struct {
+	notify func(domain string)
+}
+
+func (r *recorder) Notify(domain, provider, msg string, err error, preview bool) error {
+	r.notify(domain)
+	return nil
+}
+func (r *recorder) Done() {}
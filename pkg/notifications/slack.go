@@ -8,14 +8,14 @@ import (
 )
 
 func init() {
-	initers = append(initers, func(cfg map[string]string) Notifier {
+	initers = append(initers, func(cfg map[string]string) (Notifier, error) {
 		if url, ok := cfg["slack_url"]; ok {
 			notifier := &slackNotifier{
 				URL: url,
 			}
-			return notifier
+			return configure(cfg, "slack", notifier, markdownDigest)
 		}
-		return nil
+		return nil, nil
 	})
 }
 
@@ -25,19 +25,21 @@ type slackNotifier struct {
 }
 
 func (s *slackNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	if preview {
+		return s.send(fmt.Sprintf(`**Preview: %s[%s] -** %s`, domain, provider, msg))
+	} else if err != nil {
+		return s.send(fmt.Sprintf(`**ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err))
+	}
+	return s.send(fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg))
+}
+
+func (s *slackNotifier) send(text string) error {
 	var payload struct {
 		Username string `json:"username"`
 		Text     string `json:"text"`
 	}
 	payload.Username = "DNSControl"
-
-	if preview {
-		payload.Text = fmt.Sprintf(`**Preview: %s[%s] -** %s`, domain, provider, msg)
-	} else if err != nil {
-		payload.Text = fmt.Sprintf(`**ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err)
-	} else {
-		payload.Text = fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
-	}
+	payload.Text = text
 
 	json, _ := json.Marshal(payload)
 	_, posterr := http.Post(s.URL, "text/json", bytes.NewReader(json))
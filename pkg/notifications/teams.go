@@ -9,16 +9,16 @@ import (
 )
 
 func init() {
-	initers = append(initers, func(cfg map[string]string) Notifier {
+	initers = append(initers, func(cfg map[string]string) (Notifier, error) {
 		url, ok := cfg["teams_url"]
 		if !ok {
-			return nil
+			return nil, nil
 		}
 
 		notifier := &teamsNotifier{
 			URL: url,
 		}
-		return notifier
+		return configure(cfg, "teams", notifier, markdownDigest)
 	})
 }
 
@@ -28,22 +28,24 @@ type teamsNotifier struct {
 }
 
 func (s *teamsNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
-	var payload struct {
-		Username string `json:"username"`
-		Text     string `json:"text"`
-	}
-	payload.Username = "DnsControl"
-
 	// Format changes as 'preformated' text
 	msg = strings.ReplaceAll(msg, "\n", "\n    ")
 
 	if preview {
-		payload.Text = fmt.Sprintf("**DnsControl Preview %s**\n%s", domain, msg)
+		return s.send(fmt.Sprintf("**DnsControl Preview %s**\n%s", domain, msg))
 	} else if err != nil {
-		payload.Text = fmt.Sprintf("**DnsControl Error Making Changes %s**\n%s\nError: %s", domain, msg, err)
-	} else {
-		payload.Text = fmt.Sprintf("**DnsControl Successfully Changed %s**\n%s", domain, msg)
+		return s.send(fmt.Sprintf("**DnsControl Error Making Changes %s**\n%s\nError: %s", domain, msg, err))
 	}
+	return s.send(fmt.Sprintf("**DnsControl Successfully Changed %s**\n%s", domain, msg))
+}
+
+func (s *teamsNotifier) send(text string) error {
+	var payload struct {
+		Username string `json:"username"`
+		Text     string `json:"text"`
+	}
+	payload.Username = "DnsControl"
+	payload.Text = text
 
 	json, _ := json.Marshal(payload)
 	_, posterr := http.Post(s.URL, "text/json", bytes.NewReader(json))
@@ -9,17 +9,17 @@ import (
 )
 
 func init() {
-	initers = append(initers, func(cfg map[string]string) Notifier {
+	initers = append(initers, func(cfg map[string]string) (Notifier, error) {
 		if botToken, ok := cfg["telegram_bot_token"]; ok {
 			if chatID, ok := cfg["telegram_chat_id"]; ok {
 				notifier := &telegramNotifier{
 					BotToken: botToken,
 					ChatID:   chatID,
 				}
-				return notifier
+				return configure(cfg, "telegram", notifier, htmlDigest)
 			}
 		}
-		return nil
+		return nil, nil
 	})
 }
 
@@ -30,6 +30,15 @@ type telegramNotifier struct {
 }
 
 func (s *telegramNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	if preview {
+		return s.send(fmt.Sprintf("DNSControl preview: %s[%s]:\n%s", domain, provider, msg))
+	} else if err != nil {
+		return s.send(fmt.Sprintf("DNSControl ERROR running correction on %s[%s]:\n%s\nError: %s", domain, provider, msg, err))
+	}
+	return s.send(fmt.Sprintf("DNSControl successfully ran correction for %s[%s]:\n%s", domain, provider, msg))
+}
+
+func (s *telegramNotifier) send(text string) error {
 	var payload struct {
 		ChatID    int64  `json:"chat_id"`
 		Text      string `json:"text"`
@@ -40,14 +49,7 @@ func (s *telegramNotifier) Notify(domain, provider, msg string, err error, previ
 
 	payload.ChatID, _ = strconv.ParseInt(s.ChatID, 10, 64)
 	payload.ParseMode = "HTML"
-
-	if preview {
-		payload.Text = fmt.Sprintf("DNSControl preview: %s[%s]:\n%s", domain, provider, msg)
-	} else if err != nil {
-		payload.Text = fmt.Sprintf("DNSControl ERROR running correction on %s[%s]:\n%s\nError: %s", domain, provider, msg, err)
-	} else {
-		payload.Text = fmt.Sprintf("DNSControl successfully ran correction for %s[%s]:\n%s", domain, provider, msg)
-	}
+	payload.Text = text
 
 	marshaledPayload, _ := json.Marshal(payload)
 
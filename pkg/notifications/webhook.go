@@ -0,0 +1,128 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"time"
+)
+
+// defaultWebhookSignatureHeader is the header that carries the signature of
+// the body unless webhook_signature_header says otherwise.
+const defaultWebhookSignatureHeader = "X-DNSControl-Signature"
+
+func init() {
+	initers = append(initers, func(cfg map[string]string) (Notifier, error) {
+		if url, ok := cfg["webhook_url"]; ok {
+			notifier := &webhookNotifier{
+				URL:    url,
+				Secret: cfg["webhook_secret"],
+				Header: cfg["webhook_signature_header"],
+				RunID:  newRunID(),
+				client: &http.Client{Timeout: 30 * time.Second},
+			}
+			if notifier.Header == "" {
+				notifier.Header = defaultWebhookSignatureHeader
+			}
+			notifier.Host, _ = os.Hostname()
+			if u, err := user.Current(); err == nil {
+				notifier.User = u.Username
+			} else {
+				notifier.User = os.Getenv("USER")
+			}
+			return configure(cfg, "webhook", notifier, digestStyle{})
+		}
+		return nil, nil
+	})
+}
+
+// webhookNotifier POSTs each notification as a JSON object. If a secret is
+// configured, the body is signed with HMAC-SHA256 so that the receiver can
+// verify it.
+type webhookNotifier struct {
+	URL    string
+	Secret string
+	Header string
+	RunID  string
+	Host   string
+	User   string
+	client *http.Client
+}
+
+// WebhookPayload is the body of a webhook notification.
+type WebhookPayload struct {
+	RunID    string    `json:"run_id"` // The same for all notifications of one run.
+	Time     time.Time `json:"time"`
+	Host     string    `json:"host"`
+	User     string    `json:"user"`
+	Domain   string    `json:"domain"`
+	Provider string    `json:"provider"`
+	Action   string    `json:"action"` // "preview" or "push"
+	Message  string    `json:"message"`
+	Success  bool      `json:"success"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func (s *webhookNotifier) Notify(domain, provider, msg string, err error, preview bool) error {
+	payload := WebhookPayload{
+		RunID:    s.RunID,
+		Time:     time.Now().UTC(),
+		Host:     s.Host,
+		User:     s.User,
+		Domain:   domain,
+		Provider: provider,
+		Action:   "push",
+		Message:  msg,
+		Success:  err == nil,
+	}
+	if preview {
+		payload.Action = "preview"
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+
+	body, _ := json.Marshal(payload)
+	req, rerr := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if rerr != nil {
+		return rerr
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		req.Header.Set(s.Header, SignWebhook(s.Secret, body))
+	}
+
+	resp, posterr := s.client.Do(req)
+	if posterr != nil {
+		return posterr
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %s returned %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookNotifier) Done() {}
+
+// SignWebhook returns the signature of a webhook body: "sha256=" followed
+// by the hex-encoded HMAC-SHA256 of body, keyed with secret.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newRunID returns a random ID for this run of dnscontrol.
+func newRunID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
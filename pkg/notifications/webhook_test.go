@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_webhookNotifier(t *testing.T) {
+	var got []WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get("X-Sig"); !hmac.Equal([]byte(sig), []byte(SignWebhook("s3cret", body))) {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		var p WebhookPayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got = append(got, p)
+	}))
+	defer srv.Close()
+
+	n, _ := Init(map[string]string{
+		"webhook_url":              srv.URL,
+		"webhook_secret":           "s3cret",
+		"webhook_signature_header": "X-Sig",
+	})
+	if err := n.Notify("example.com", "r53", "\x1b[32mCREATE\x1b[0m www", nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify("example.com", "r53", "CREATE www", errors.New("boom"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(got))
+	}
+	if got[0].Action != "preview" || !got[0].Success || got[0].Message != "CREATE www" || got[0].Domain != "example.com" || got[0].Provider != "r53" {
+		t.Errorf("preview payload: %+v", got[0])
+	}
+	if got[1].Action != "push" || got[1].Success || got[1].Error != "boom" {
+		t.Errorf("push payload: %+v", got[1])
+	}
+	if got[0].RunID == "" || got[0].RunID != got[1].RunID {
+		t.Errorf("run IDs: %q %q", got[0].RunID, got[1].RunID)
+	}
+
+	// A wrong secret is rejected by the server, and reported.
+	n, _ = Init(map[string]string{
+		"webhook_url":              srv.URL,
+		"webhook_secret":           "wrong",
+		"webhook_signature_header": "X-Sig",
+	})
+	if err := n.Notify("example.com", "r53", "CREATE www", nil, false); err == nil {
+		t.Error("expected an error for a rejected notification")
+	}
+}
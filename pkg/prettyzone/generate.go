@@ -0,0 +1,196 @@
+package prettyzone
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/txtutil"
+)
+
+// Collapsing records into $GENERATE directives.
+//
+// A run of records such as:
+//
+//	host-1  IN A 192.0.2.11
+//	host-2  IN A 192.0.2.12
+//	host-3  IN A 192.0.2.13
+//
+// is written as:
+//
+//	$GENERATE 1-3 host-$ IN A 192.0.2.${10}
+//
+// The number that varies is the first number in the label. The target may
+// contain the same number plus a constant offset, or be the same for all
+// the records. Only A, CNAME and PTR records are collapsed, and only runs of
+// at least minGenerateRun consecutive numbers without leading zeros.
+
+// minGenerateRun is the shortest run of records that is collapsed.
+const minGenerateRun = 3
+
+// generateTypes are the types of the records that may be collapsed.
+var generateTypes = map[string]bool{"A": true, "CNAME": true, "PTR": true}
+
+var firstNumber = regexp.MustCompile(`[0-9]+`)
+
+// generateKey identifies the records that one $GENERATE can produce.
+type generateKey struct {
+	rtype      string
+	ttl        uint32
+	namePrefix string
+	nameSuffix string
+	target     string // The target, with "\x00" where the number goes.
+	offset     int64
+}
+
+// generateLine is a $GENERATE directive, and the records it replaces.
+type generateLine struct {
+	key         generateKey
+	start, stop int64
+	records     []*models.RecordConfig
+}
+
+// number parses a run of digits without leading zeros.
+func number(digits string) (int64, bool) {
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(digits, 10, 32)
+	return n, err == nil
+}
+
+// generateKeys returns the $GENERATE templates that can produce rec, along
+// with the number that produces it.
+func generateKeys(rec *models.RecordConfig, target string) ([]generateKey, int64) {
+	// Records with a comment (CF_PROXY_ON) are written one by one.
+	if !generateTypes[rec.Type] || rec.Metadata["cloudflare_proxy"] == "true" || strings.ContainsAny(rec.Name+target, "${}\\\x00") {
+		return nil, 0
+	}
+	loc := firstNumber.FindStringIndex(rec.Name)
+	if loc == nil {
+		return nil, 0
+	}
+	n, ok := number(rec.Name[loc[0]:loc[1]])
+	if !ok {
+		return nil, 0
+	}
+	base := generateKey{
+		rtype:      rec.Type,
+		ttl:        rec.TTL,
+		namePrefix: rec.Name[:loc[0]],
+		nameSuffix: rec.Name[loc[1]:],
+	}
+
+	// The same target for all.
+	k := base
+	k.target = target
+	keys := []generateKey{k}
+	// A number in the target.
+	for _, tloc := range firstNumber.FindAllStringIndex(target, -1) {
+		v, ok := number(target[tloc[0]:tloc[1]])
+		if !ok {
+			continue
+		}
+		k := base
+		k.target = target[:tloc[0]] + "\x00" + target[tloc[1]:]
+		k.offset = v - n
+		keys = append(keys, k)
+	}
+	return keys, n
+}
+
+// findGenerateLines finds the runs of records that can be collapsed. It
+// returns the $GENERATE lines, and the records they replace.
+func findGenerateLines(records models.Records) ([]*generateLine, map[*models.RecordConfig]*generateLine) {
+	type member struct {
+		n   int64
+		rec *models.RecordConfig
+	}
+	groups := map[generateKey][]member{}
+	for _, rec := range records {
+		keys, n := generateKeys(rec, rec.GetTargetCombinedFunc(txtutil.EncodeQuoted))
+		for _, k := range keys {
+			groups[k] = append(groups[k], member{n, rec})
+		}
+	}
+
+	// Find the runs of consecutive numbers in each group.
+	var candidates []*generateLine
+	for k, members := range groups {
+		if len(members) < minGenerateRun {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].n < members[j].n })
+		for i := 0; i < len(members); {
+			j := i + 1
+			for j < len(members) && members[j].n == members[j-1].n+1 {
+				j++
+			}
+			if j-i >= minGenerateRun {
+				l := &generateLine{key: k, start: members[i].n, stop: members[j-1].n}
+				for _, m := range members[i:j] {
+					l.records = append(l.records, m.rec)
+				}
+				candidates = append(candidates, l)
+			}
+			i = j
+		}
+	}
+
+	// A record can be in several candidates. Prefer the longest runs, then
+	// a stable order.
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if len(a.records) != len(b.records) {
+			return len(a.records) > len(b.records)
+		}
+		return a.String() < b.String()
+	})
+	used := map[*models.RecordConfig]*generateLine{}
+	var lines []*generateLine
+	for _, l := range candidates {
+		// Keep the longest run of records not used yet.
+		var best, cur []*models.RecordConfig
+		bestStart, curStart := int64(0), int64(0)
+		for i, rec := range l.records {
+			if used[rec] != nil {
+				cur = nil
+				continue
+			}
+			if cur == nil {
+				curStart = l.start + int64(i)
+			}
+			cur = append(cur, rec)
+			if len(cur) > len(best) {
+				best, bestStart = cur, curStart
+			}
+		}
+		if len(best) < minGenerateRun {
+			continue
+		}
+		l.records, l.start, l.stop = best, bestStart, bestStart+int64(len(best))-1
+		for _, rec := range l.records {
+			used[rec] = l
+		}
+		lines = append(lines, l)
+	}
+	return lines, used
+}
+
+// String returns the $GENERATE directive. The TTL is always written: not
+// all parsers apply $TTL to the records of a $GENERATE.
+func (l *generateLine) String() string {
+	sub := "$"
+	if l.key.offset != 0 {
+		sub = fmt.Sprintf("${%d}", l.key.offset)
+	}
+	return "$GENERATE " + FormatLine([]int{10, 10, 5, 2, 5, 0}, []string{
+		fmt.Sprintf("%d-%d", l.start, l.stop),
+		l.key.namePrefix + "$" + l.key.nameSuffix,
+		strconv.FormatUint(uint64(l.key.ttl), 10), "IN", l.key.rtype,
+		strings.ReplaceAll(l.key.target, "\x00", sub),
+	})
+}
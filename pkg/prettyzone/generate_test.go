@@ -0,0 +1,96 @@
+package prettyzone
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func parseRRs(t *testing.T, zone string) []dns.RR {
+	t.Helper()
+	zp := dns.NewZoneParser(strings.NewReader(zone), "example.com.", "example.com.zone")
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return rrs
+}
+
+func rrStrings(rrs []dns.RR) []string {
+	var s []string
+	for _, rr := range rrs {
+		s = append(s, strings.ToLower(rr.String()))
+	}
+	slices.Sort(s)
+	return s
+}
+
+func TestWriteZoneFileRCGenerate(t *testing.T) {
+	const zone = `$TTL 300
+@        IN NS    ns1.example.com.
+host-1   IN A     192.0.2.11
+host-2   IN A     192.0.2.12
+host-3   IN A     192.0.2.13
+host-4   IN A     192.0.2.14
+host-6   IN A     192.0.2.16
+alias1   IN CNAME www.example.com.
+alias2   IN CNAME www.example.com.
+alias3   IN CNAME www.example.com.
+dyn7     600 IN CNAME dyn7.pool.example.net.
+dyn8     600 IN CNAME dyn8.pool.example.net.
+dyn9     600 IN CNAME dyn9.pool.example.net.
+short1   IN A     192.0.2.100
+short2   IN A     192.0.2.101
+pad01    IN A     192.0.2.201
+pad02    IN A     192.0.2.202
+pad03    IN A     192.0.2.203
+txt1     IN TXT   "1"
+txt2     IN TXT   "2"
+txt3     IN TXT   "3"
+`
+	rrs := parseRRs(t, zone)
+	rcs, err := rrstoRCs(rrs, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteZoneFileRCGenerate(&buf, rcs, "example.com", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"$GENERATE 1-4        host-$     300   IN A     192.0.2.${10}\n",
+		"$GENERATE 1-3        alias$     300   IN CNAME www.example.com.\n",
+		"$GENERATE 7-9        dyn$       600   IN CNAME dyn$.pool.example.net.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	for _, notWant := range []string{"short$", "pad$", "txt$", "1-5"} {
+		if strings.Contains(got, notWant) {
+			t.Errorf("%s collapsed", notWant)
+		}
+	}
+
+	// The zone file has the same records.
+	if g, w := rrStrings(parseRRs(t, got)), rrStrings(rrs); !slices.Equal(g, w) {
+		t.Errorf("records differ:\ngot  %v\nwant %v", g, w)
+	}
+
+	// Without the opt-in, nothing is collapsed.
+	buf.Reset()
+	if err := WriteZoneFileRC(&buf, rcs, "example.com", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "$GENERATE") {
+		t.Errorf("WriteZoneFileRC wrote $GENERATE")
+	}
+}
@@ -67,6 +67,15 @@ func WriteZoneFileRC(w io.Writer, records models.Records, origin string, default
 	return z.generateZoneFileHelper(w)
 }
 
+// WriteZoneFileRCGenerate is WriteZoneFileRC, but runs of records that
+// differ by a number (host-1, host-2, ...) are written as $GENERATE
+// directives.
+func WriteZoneFileRCGenerate(w io.Writer, records models.Records, origin string, defaultTTL uint32, comments []string) error {
+	z := PrettySort(records, origin, defaultTTL, comments)
+	z.Generate = true
+	return z.generateZoneFileHelper(w)
+}
+
 // PrettySort sorts the records in a pretty order.
 func PrettySort(records models.Records, origin string, defaultTTL uint32, comments []string) *ZoneGenData {
 	if defaultTTL == 0 {
@@ -102,7 +111,23 @@ func (z *ZoneGenData) generateZoneFileHelper(w io.Writer) error {
 			}
 		}
 	}
+	var used map[*models.RecordConfig]*generateLine
+	if z.Generate {
+		_, used = findGenerateLines(z.Records)
+	}
+	written := map[*generateLine]bool{}
 	for i, rr := range z.Records {
+		if l := used[rr]; l != nil {
+			// The record is written as part of a $GENERATE directive,
+			// where the first of its records would be.
+			if !written[l] {
+				written[l] = true
+				fmt.Fprintln(w, l)
+			}
+			nameShortPrevious = ""
+			continue
+		}
+
 		// Fake types are commented out.
 		prefix := ""
 		_, ok := dns.StringToType[rr.Type]
@@ -18,6 +18,7 @@ type ZoneGenData struct {
 	DefaultTTL uint32
 	Records    models.Records
 	Comments   []string
+	Generate   bool // Collapse runs of records into $GENERATE directives.
 }
 
 func (z *ZoneGenData) Len() int      { return len(z.Records) }
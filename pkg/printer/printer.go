@@ -2,6 +2,7 @@ package printer
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -65,6 +66,25 @@ func PrintfIf(prnt bool, fmt string, args ...interface{}) {
 	DefaultPrinter.PrintfIf(prnt, fmt, args...)
 }
 
+// printerKey is the context key of the Printer set by WithPrinter.
+type printerKey struct{}
+
+// WithPrinter returns a copy of ctx that carries p. dnscontrol runs the
+// corrections of a zone with the zone's printer in their context, so that
+// when zones are processed in parallel, a provider that prints with
+// FromContext keeps its output with the rest of the zone's output.
+func WithPrinter(ctx context.Context, p Printer) context.Context {
+	return context.WithValue(ctx, printerKey{}, p)
+}
+
+// FromContext returns the Printer carried by ctx, or DefaultPrinter.
+func FromContext(ctx context.Context) Printer {
+	if p, ok := ctx.Value(printerKey{}).(Printer); ok {
+		return p
+	}
+	return DefaultPrinter
+}
+
 // DefaultPrinter is the default Printer, used by Debugf, Printf, and Warnf.
 var DefaultPrinter = &ConsolePrinter{
 	Reader:  bufio.NewReader(os.Stdin),
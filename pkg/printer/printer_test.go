@@ -2,6 +2,7 @@ package printer
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,3 +46,12 @@ func TestVerbose(t *testing.T) {
 	p.Debugf("more debugging\n")
 	assert.Equal(t, "WARNING: a dire warning!\noutput\nmore debugging\n", output.String())
 }
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Printer(DefaultPrinter), FromContext(context.Background()))
+
+	output := &bytes.Buffer{}
+	p := &ConsolePrinter{Writer: output}
+	FromContext(WithPrinter(context.Background(), p)).Printf("zone %s\n", "example.com")
+	assert.Equal(t, "zone example.com\n", output.String())
+}
@@ -0,0 +1,94 @@
+// Package zoneglob matches zone names against globs. It is used wherever
+// zones are picked by a glob: --domains, the _depopulate_allow and
+// _depopulate_deny settings, and the _zones setting of notifications, so
+// that a glob means the same everywhere:
+//
+//	"*"      any characters, including dots ("*.example.com" matches
+//	         "a.b.example.com")
+//	"?"      any one character
+//	"[abc]"  one of the characters
+//	"{a,b}"  one of the patterns
+//
+// A glob matches the whole name. Case is ignored.
+//
+// In a comma-separated list of globs (see SplitList), the commas inside
+// "{a,b}" do not separate the globs.
+package zoneglob
+
+import (
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// Matcher reports whether a zone name matches a glob.
+type Matcher func(name string) bool
+
+// Compile compiles a glob.
+func Compile(pattern string) (Matcher, error) {
+	g, err := glob.Compile(strings.ToLower(pattern))
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) bool { return g.Match(strings.ToLower(name)) }, nil
+}
+
+// SplitList splits a comma-separated list at the commas that are not
+// inside braces, so that "{a,b}.com,c.com" is "{a,b}.com" and "c.com" (and
+// so is a regular expression such as "re:^a{1,3}$"). The items are trimmed
+// and empty items are skipped.
+func SplitList(list string) []string {
+	var items []string
+	depth, start := 0, 0
+	add := func(item string) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	for i, r := range list {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+		case r == ',' && depth == 0:
+			add(list[start:i])
+			start = i + 1
+		}
+	}
+	add(list[start:])
+	return items
+}
+
+// CompileList compiles a comma-separated list of globs (see SplitList).
+func CompileList(list string) ([]Matcher, error) {
+	var ms []Matcher
+	for _, item := range SplitList(list) {
+		m, err := Compile(item)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// MatchAny reports whether name matches any of ms.
+func MatchAny(ms []Matcher, name string) bool {
+	for _, m := range ms {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether name matches pattern. An invalid pattern only
+// matches itself.
+func Match(pattern, name string) bool {
+	m, err := Compile(pattern)
+	if err != nil {
+		return strings.EqualFold(pattern, name)
+	}
+	return m(name)
+}
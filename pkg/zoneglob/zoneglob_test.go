@@ -0,0 +1,66 @@
+package zoneglob
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "Example.COM", true},
+		{"*.com", "example.com", true},
+		{"*.com", "a.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"example.*", "example.co.uk", true},
+		{"ex?mple.com", "example.com", true},
+		{"{a,b}.example.com", "b.example.com", true},
+		{"[ab].example.com", "c.example.com", false},
+		{"[oops", "[oops", true},
+	} {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileList(t *testing.T) {
+	ms, err := CompileList(" *.com, example.org ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("got %d globs, want 2", len(ms))
+	}
+	if !MatchAny(ms, "a.example.com") || !MatchAny(ms, "example.org") || MatchAny(ms, "example.net") {
+		t.Error("MatchAny: wrong result")
+	}
+	if _, err := CompileList("[oops"); err == nil {
+		t.Error("invalid glob: no error")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	for list, want := range map[string]string{
+		"a.com, b.com,":           "a.com|b.com",
+		"{a,b}.example.com,c.com": "{a,b}.example.com|c.com",
+		`re:^a{1,3}\.com$,x`:      `re:^a{1,3}\.com$|x`,
+		"{a,{b,c}}.com":           "{a,{b,c}}.com",
+		"":                        "",
+	} {
+		if got := strings.Join(SplitList(list), "|"); got != want {
+			t.Errorf("SplitList(%q) = %q, want %q", list, got, want)
+		}
+	}
+
+	ms, err := CompileList("{a,b}.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || !ms[0]("b.example.com") || ms[0]("c.example.com") {
+		t.Error("CompileList split the alternation")
+	}
+}
@@ -1,17 +1,75 @@
 package zonerecs
 
 import (
+	"context"
+	"sync"
+
 	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
 )
 
+// Result is everything CorrectZoneRecordsDetailed learned about a zone.
+type Result struct {
+	Existing          models.Records       // The records returned by GetZoneRecords.
+	Desired           *models.DomainConfig // The copy of dc given to GetZoneRecordsCorrections.
+	Changes           diff2.ChangeList     // Record-level changes (provider-neutral). Use RecordChanges.
+	Reports           []*models.Correction // Informational messages.
+	Corrections       []*models.Correction // Actions.
+	ActualChangeCount int
+
+	changesOnce sync.Once
+}
+
+// RecordChanges returns the record-level changes. They are computed with
+// diff2.ByRecord the first time they are needed, after the provider has
+// generated its corrections, so that any adjustments the provider made to
+// the desired records (for example, rounding TTLs) are reflected.
+//
+// The list is an approximation of what the provider will do: providers
+// that work by record set or by zone, or that compare records their own
+// way, may make different changes. If the list can not be computed, a
+// warning is printed and the list is empty.
+func (r *Result) RecordChanges() diff2.ChangeList {
+	r.changesOnce.Do(func() {
+		if r.Changes != nil || r.Desired == nil {
+			return
+		}
+		changes, _, err := diff2.ByRecord(r.Existing, r.Desired, nil)
+		if err != nil {
+			printer.Warnf("%s: could not list the record changes: %v\n", r.Desired.Name, err)
+			return
+		}
+		r.Changes = changes
+	})
+	return r.Changes
+}
+
 // CorrectZoneRecords calls both GetZoneRecords, does any
 // post-processing, and then calls GetZoneRecordsCorrections.  The
 // name sucks because all the good names were taken.
 func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*models.Correction, []*models.Correction, int, error) {
-	existingRecords, err := driver.GetZoneRecords(dc.Name, dc.Metadata)
-	if err != nil {
+	r, err := CorrectZoneRecordsDetailed(driver, dc)
+	if r == nil {
 		return nil, nil, 0, err
 	}
+	return r.Reports, r.Corrections, r.ActualChangeCount, err
+}
+
+// CorrectZoneRecordsDetailed is like CorrectZoneRecords but also returns
+// the existing records, from which a record-level list of changes can be
+// computed. See Result.RecordChanges.
+func CorrectZoneRecordsDetailed(driver models.DNSProvider, dc *models.DomainConfig) (*Result, error) {
+	return CorrectZoneRecordsContext(context.Background(), driver, dc)
+}
+
+// CorrectZoneRecordsContext is like CorrectZoneRecordsDetailed but gives
+// up when ctx is done.
+func CorrectZoneRecordsContext(ctx context.Context, driver models.DNSProvider, dc *models.DomainConfig) (*Result, error) {
+	existingRecords, err := models.GetZoneRecordsContext(ctx, driver, dc.Name, dc.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
 	// downcase
 	models.Downcase(existingRecords)
@@ -25,19 +83,26 @@ func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*
 	// dc.Records.
 	dc, err = dc.Copy()
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 
 	// punycode
 	if err := dc.Punycode(); err != nil {
-		return nil, nil, 0, err
+		return nil, err
 	}
 	// FIXME(tlim) It is a waste to PunyCode every iteration.
 	// This should be moved to where the JavaScript is processed.
 
-	everything, actualChangeCount, err := driver.GetZoneRecordsCorrections(dc, existingRecords)
+	everything, actualChangeCount, err := models.GetZoneRecordsCorrectionsContext(ctx, driver, dc, existingRecords)
 	reports, corrections := splitReportsAndCorrections(everything)
-	return reports, corrections, actualChangeCount, err
+	result := &Result{
+		Existing:          existingRecords,
+		Desired:           dc,
+		Reports:           reports,
+		Corrections:       corrections,
+		ActualChangeCount: actualChangeCount,
+	}
+	return result, err
 }
 
 func splitReportsAndCorrections(everything []*models.Correction) (reports, corrections []*models.Correction) {
@@ -14,10 +14,14 @@ bind -
 */
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,13 +31,14 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/pkg/prettyzone"
 	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
 	"github.com/StackExchange/dnscontrol/v4/providers"
+	"github.com/google/shlex"
 	"github.com/miekg/dns"
 )
 
 var features = providers.DocumentationNotes{
 	// The default for unlisted capabilities is 'Cannot'.
 	// See providers/capabilities.go for the entire list of capabilities.
-	providers.CanAutoDNSSEC:          providers.Can("Just writes out a comment indicating DNSSEC was requested"),
+	providers.CanAutoDNSSEC:          providers.Can("Signs the zone files if dnssec_key_dir is set; otherwise just writes out a comment indicating DNSSEC was requested"),
 	providers.CanGetZones:            providers.Can(),
 	providers.CanConcur:              providers.Can(),
 	providers.CanUseCAA:              providers.Can(),
@@ -62,6 +67,10 @@ func initBind(config map[string]string, providermeta json.RawMessage) (providers
 	api := &bindProvider{
 		directory:      config["directory"],
 		filenameformat: config["filenameformat"],
+		namedConf:      config["named_conf"],
+		namedZoneDir:   config["named_zone_dir"],
+		postWrite:      config["post_write"],
+		dnssecKeyDir:   config["dnssec_key_dir"],
 	}
 	if api.directory == "" {
 		api.directory = "zones"
@@ -75,6 +84,30 @@ func initBind(config map[string]string, providermeta json.RawMessage) (providers
 			return nil, err
 		}
 	}
+	if api.ZoneType == "" {
+		api.ZoneType = "primary"
+	}
+	if !slices.Contains(zoneTypes, api.ZoneType) {
+		return nil, fmt.Errorf("zone_type (%v) must be one of: %s", api.ZoneType, strings.Join(zoneTypes, ", "))
+	}
+	if b := config["backups"]; b != "" {
+		n, err := strconv.Atoi(b)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("backups (%v) must be a number, 0 or more", b)
+		}
+		api.backups = n
+	}
+	if err := bindserial.CheckStrategy(api.SerialStrategy); err != nil {
+		return nil, err
+	}
+	if err := api.initSignOptions(); err != nil {
+		return nil, err
+	}
+	if api.postWrite != "" {
+		if args, err := shlex.Split(api.postWrite); err != nil || len(args) == 0 {
+			return nil, fmt.Errorf("invalid post_write command %q", api.postWrite)
+		}
+	}
 	var nss []string
 	for i, ns := range api.DefaultNS {
 		if ns == "" {
@@ -93,6 +126,16 @@ func initBind(config map[string]string, providermeta json.RawMessage) (providers
 	return api, err
 }
 
+var credsKeys = providers.CredsKeys{
+	"directory":      providers.CredsOptional,
+	"filenameformat": providers.CredsOptional,
+	"named_conf":     providers.CredsOptional,
+	"named_zone_dir": providers.CredsOptional,
+	"post_write":     providers.CredsOptional,
+	"dnssec_key_dir": providers.CredsOptional,
+	"backups":        providers.CredsOptional,
+}
+
 func init() {
 	const providerName = "BIND"
 	const providerMaintainer = "@tlimoncelli"
@@ -100,7 +143,7 @@ func init() {
 		Initializer:   initBind,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, credsKeys)
 	providers.RegisterMaintainer(providerName, providerMaintainer)
 }
 
@@ -122,11 +165,31 @@ func (s SoaDefaults) String() string {
 
 // bindProvider is the provider handle for the bindProvider driver.
 type bindProvider struct {
-	DefaultNS      []string    `json:"default_ns"`
-	DefaultSoa     SoaDefaults `json:"default_soa"`
-	nameservers    []*models.Nameserver
-	directory      string
-	filenameformat string
+	DefaultNS     []string    `json:"default_ns"`
+	DefaultSoa    SoaDefaults `json:"default_soa"`
+	ZoneType      string      `json:"zone_type"`
+	AlsoNotify    []string    `json:"also_notify"`
+	AllowTransfer []string    `json:"allow_transfer"`
+	// SerialStrategy picks the next SOA serial. See bindserial.Next.
+	SerialStrategy string `json:"serial_strategy"`
+	// Secondaries are queried for their serial, to warn when it is ahead.
+	Secondaries []string `json:"secondaries"`
+	// Generate writes runs of similar records as $GENERATE directives.
+	Generate bool `json:"generate"`
+	// Inline DNSSEC signing. See dnssec.go.
+	NSEC3             bool   `json:"dnssec_nsec3"`
+	NSEC3Iterations   uint16 `json:"nsec3_iterations"`
+	NSEC3Salt         string `json:"nsec3_salt"`
+	SignatureValidity int    `json:"signature_validity_days"`
+	nameservers       []*models.Nameserver
+	directory         string
+	filenameformat    string
+	namedConf         string // The named.conf include to maintain, if any.
+	namedZoneDir      string // The zone file directory as seen by named.
+	postWrite         string // The command to run after a zone is written.
+	dnssecKeyDir      string // The DNSSEC keys, if zones are signed.
+	signOptions       signOptions
+	backups           int // The number of previous versions of zone files to keep.
 }
 
 // GetNameservers returns the nameservers for a domain.
@@ -138,8 +201,24 @@ func (c *bindProvider) GetNameservers(string) ([]*models.Nameserver, error) {
 	return models.ToNameservers(r)
 }
 
-// ListZones returns all the zones in an account
+// ListZones returns all the zones in an account: the zones listed in the
+// named.conf include, or else the zones that have a zone file.
 func (c *bindProvider) ListZones() ([]string, error) {
+	if c.namedConf != "" {
+		zones, err := readNamedConf(c.namedConf)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(zones))
+		for uniquename := range zones {
+			// Strip the tag (if any). ListZones returns zone names.
+			name, _, _ := strings.Cut(uniquename, "!")
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return slices.Compact(names), nil
+	}
+
 	if _, err := os.Stat(c.directory); os.IsNotExist(err) {
 		return nil, fmt.Errorf("directory %q does not exist", c.directory)
 	}
@@ -155,6 +234,9 @@ func (c *bindProvider) ListZones() ([]string, error) {
 		return files, fmt.Errorf("bind ListZones readdir %q: %w",
 			c.directory, err)
 	}
+	filenames = slices.DeleteFunc(filenames, func(name string) bool {
+		return isBackupOrTemp(name) || strings.HasSuffix(name, signedSuffix)
+	})
 
 	return extractZonesFromFilenames(c.filenameformat, filenames), nil
 }
@@ -190,12 +272,29 @@ func (c *bindProvider) GetZoneRecords(domain string, meta map[string]string) (mo
 		return nil, fmt.Errorf("can't open %s: %w", zonefile, err)
 	}
 
-	return ParseZoneContents(string(content), domain, zonefile)
+	return parseZoneContents(string(content), domain, zonefile, c.directory)
 }
 
 // ParseZoneContents parses a string as a BIND zone and returns the records.
+// $GENERATE is expanded. $INCLUDE is not allowed: the content may come
+// from anywhere (for example an --existing-from snapshot).
 func ParseZoneContents(content string, zoneName string, zonefileName string) (models.Records, error) {
-	zp := dns.NewZoneParser(strings.NewReader(content), zoneName, zonefileName)
+	return parseZoneContents(content, zoneName, zonefileName, "")
+}
+
+// parseZoneContents is ParseZoneContents for the zone files of the
+// provider. If includeDir is not "", $INCLUDE is allowed and relative
+// paths are resolved relative to includeDir, as named resolves them
+// relative to its working directory.
+func parseZoneContents(content string, zoneName string, zonefileName string, includeDir string) (models.Records, error) {
+	parserFile := zonefileName
+	if includeDir != "" {
+		// The parser resolves relative $INCLUDE paths relative to the
+		// directory of the file it is given.
+		parserFile = filepath.Join(includeDir, filepath.Base(zonefileName))
+	}
+	zp := dns.NewZoneParser(strings.NewReader(content), zoneName, parserFile)
+	zp.SetIncludeAllowed(includeDir != "")
 
 	foundRecords := models.Records{}
 	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
@@ -212,10 +311,36 @@ func ParseZoneContents(content string, zoneName string, zonefileName string) (mo
 	return foundRecords, nil
 }
 
+// EnsureZoneExists does nothing. The zone file, and the zone's entry in
+// the named.conf include, are written by the zone's corrections, which know
+// its split horizon tag.
 func (c *bindProvider) EnsureZoneExists(_ string) error {
 	return nil
 }
 
+// DeleteZone removes the zone file of a zone, and the zone from the
+// named.conf include. Only untagged zones can be deleted since the tag can
+// not be recovered from the zone's name.
+func (c *bindProvider) DeleteZone(domain string) error {
+	zonefile := filepath.Join(c.directory, makeFileName(c.filenameformat, domain, domain, ""))
+	printer.Printf("DELETING ZONEFILE: %v\n", zonefile)
+	if err := os.Remove(filepath.FromSlash(zonefile)); err != nil {
+		return fmt.Errorf("could not delete zonefile: %w", err)
+	}
+	if err := os.Remove(filepath.FromSlash(zonefile + signedSuffix)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not delete signed zonefile: %w", err)
+	}
+	if c.namedConf != "" {
+		if err := c.removeFromNamedConf(domain); err != nil {
+			return err
+		}
+	}
+	if c.postWrite != "" {
+		return c.runPostWrite(context.Background(), printer.DefaultPrinter, domain, domain, "")
+	}
+	return nil
+}
+
 // GetZoneRecordsCorrections returns a list of corrections that will turn existing records into dc.Records.
 func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundRecords models.Records) ([]*models.Correction, int, error) {
 	var corrections []*models.Correction
@@ -239,7 +364,10 @@ func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundR
 			break
 		}
 	}
-	soaRec, nextSerial := makeSoa(dc.Name, &c.DefaultSoa, foundSoa, desiredSoa)
+	soaRec, nextSerial, err := makeSoa(dc.Name, &c.DefaultSoa, foundSoa, desiredSoa, c.SerialStrategy)
+	if err != nil {
+		return nil, 0, err
+	}
 	if desiredSoa == nil {
 		dc.Records = append(dc.Records, soaRec)
 		desiredSoa = dc.Records[len(dc.Records)-1]
@@ -254,16 +382,64 @@ func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundR
 		return nil, 0, err
 	}
 	msgs, changes, actualChangeCount = result.Msgs, result.HasChanges, result.ActualChangeCount
-	if !changes {
+
+	zonefile = filepath.Join(c.directory,
+		makeFileName(c.filenameformat,
+			dc.Metadata[models.DomainUniqueName], dc.Name, dc.Metadata[models.DomainTag]),
+	)
+
+	// If the zone is signed, the signed file is also written when its
+	// signatures are about to expire or the keys changed. The zone file
+	// is written again with a new serial, so that the secondaries
+	// transfer the new signatures.
+	signing := c.dnssecKeyDir != "" && dc.AutoDNSSEC == "on"
+	var keys []*dnssecKey
+	if signing {
+		keys, err = readDNSSECKeys(c.dnssecKeyDir, dc.Name)
+		if err != nil {
+			return nil, 0, err
+		}
+		if reason := needsResigning(zonefile+signedSuffix, keys, c.signOptions, time.Now()); !changes && reason != "" {
+			msgs = append(msgs, fmt.Sprintf("SIGN %s: %s", dc.Name, reason))
+			changes = true
+			actualChangeCount++
+		}
+	}
+
+	// The zone must be listed in the named.conf include, with its file.
+	var confFile string
+	confChanges := false
+	uniquename := dc.Metadata[models.DomainUniqueName]
+	if uniquename == "" {
+		uniquename = dc.Name
+	}
+	if c.namedConf != "" {
+		confFile, err = c.namedConfFile(uniquename, dc.Name, dc.Metadata[models.DomainTag])
+		if err != nil {
+			return nil, 0, err
+		}
+		if signing {
+			confFile += signedSuffix
+		}
+		confChanges, err = c.addToNamedConf(uniquename, confFile, false)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if !changes && !confChanges {
 		return nil, 0, nil
 	}
+	if confChanges {
+		msgs = append(msgs, fmt.Sprintf("UPDATE named.conf include %s for %s", c.namedConf, dc.Name))
+		actualChangeCount++
+	}
 	msg = strings.Join(msgs, "\n")
 
 	comments := make([]string, 0, 5)
 	comments = append(comments,
 		"generated with dnscontrol "+time.Now().Format(time.RFC3339),
 	)
-	if dc.AutoDNSSEC == "on" {
+	if dc.AutoDNSSEC == "on" && !signing {
 		// This does nothing but reminds the user to add the correct
 		// auto-dnssecc zone statement to named.conf.
 		// While it is a no-op, it is useful for situations where a zone
@@ -271,50 +447,69 @@ func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundR
 		comments = append(comments, "Automatic DNSSEC signing requested")
 	}
 
-	zonefile = filepath.Join(c.directory,
-		makeFileName(c.filenameformat,
-			dc.Metadata[models.DomainUniqueName], dc.Name, dc.Metadata[models.DomainTag]),
-	)
-
 	// We only change the serial number if there is a change.
+	// (If the --bindserial flag is used, nextSerial is that value.)
 	desiredSoa.SoaSerial = nextSerial
 
-	// If the --bindserial flag is used, force the serial to that value
-	if bindserial.ForcedValue != 0 {
-		desiredSoa.SoaSerial = uint32(bindserial.ForcedValue & 0xFFFF)
+	// Warn now, so that preview shows a secondary that won't transfer
+	// the zone before push writes it.
+	if changes {
+		c.checkSecondaries(dc.Name, nextSerial)
 	}
 
 	corrections = append(corrections,
-		&models.Correction{
-			Msg: msg,
-			F: func() error {
-				printer.Printf("WRITING ZONEFILE: %v\n", zonefile)
-				fname, err := preprocessFilename(zonefile)
-				if err != nil {
-					return fmt.Errorf("could not create zonefile: %w", err)
-				}
-				zf, err := os.Create(fname)
-				if err != nil {
-					return fmt.Errorf("could not create zonefile: %w", err)
+		models.NewCorrectionContext(msg, func(ctx context.Context) error {
+			// Print with the zone's printer, which keeps the output
+			// together when zones are pushed in parallel.
+			p := printer.FromContext(ctx)
+			if changes {
+				if err := c.writeZoneFile(p, zonefile, result.DesiredPlus, dc.Name, comments); err != nil {
+					return err
 				}
-				// Beware that if there are any fake types, then they will
-				// be commented out on write, but we don't reverse that when
-				// reading, so there will be a diff on every invocation.
-				err = prettyzone.WriteZoneFileRC(zf, result.DesiredPlus, dc.Name, 0, comments)
-				if err != nil {
-					return fmt.Errorf("failed WriteZoneFile: %w", err)
+				if signing {
+					if err := writeSignedZoneFile(p, zonefile, dc.Name, keys, c.signOptions, time.Now()); err != nil {
+						return err
+					}
 				}
-				err = zf.Close()
-				if err != nil {
-					return fmt.Errorf("closing: %w", err)
+			}
+			if confChanges {
+				p.Printf("WRITING NAMED.CONF INCLUDE: %v\n", c.namedConf)
+				if _, err := c.addToNamedConf(uniquename, confFile, true); err != nil {
+					return err
 				}
-				return nil
-			},
-		})
+			}
+			if c.postWrite != "" {
+				return c.runPostWrite(ctx, p, dc.Metadata[models.DomainUniqueName], dc.Name, dc.Metadata[models.DomainTag])
+			}
+			return nil
+		}))
 
 	return corrections, actualChangeCount, nil
 }
 
+// writeZoneFile writes the records of a zone to zonefile, keeping backups
+// of the previous versions.
+func (c *bindProvider) writeZoneFile(p printer.Printer, zonefile string, records models.Records, origin string, comments []string) error {
+	p.Printf("WRITING ZONEFILE: %v\n", zonefile)
+	var b bytes.Buffer
+	// Beware that if there are any fake types, then they will
+	// be commented out on write, but we don't reverse that when
+	// reading, so there will be a diff on every invocation.
+	write := prettyzone.WriteZoneFileRC
+	if c.Generate {
+		write = prettyzone.WriteZoneFileRCGenerate
+	}
+	err := write(&b, records, origin, 0, comments)
+	if err != nil {
+		return fmt.Errorf("failed WriteZoneFile: %w", err)
+	}
+	check := func(content []byte) error { return checkZoneContents(content, origin, zonefile) }
+	if err := writeFileAtomic(zonefile, b.Bytes(), check, c.backups); err != nil {
+		return fmt.Errorf("could not write zonefile: %w", err)
+	}
+	return nil
+}
+
 // preprocessFilename pre-processes a filename we're about to os.Create()
 // * On Windows systems, it translates the separator.
 // * It attempts to mkdir the directories leading up to the filename.
@@ -0,0 +1,53 @@
+package bind
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_parseZoneContents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "zones"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Relative to the directory, as named would resolve it.
+	if err := os.WriteFile(filepath.Join(dir, "hosts.inc"), []byte("www 300 IN A 192.0.2.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const zone = `$TTL 300
+@ IN NS ns1.example.com.
+$INCLUDE hosts.inc
+$GENERATE 1-3 host-$ 300 IN A 192.0.2.${10}
+`
+	records, err := parseZoneContents(zone, "example.com", filepath.Join(dir, "zones", "example.com.zone"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rec := range records {
+		got = append(got, rec.GetLabel()+" "+rec.Type+" "+rec.GetTargetField())
+	}
+	slices.Sort(got)
+	want := []string{
+		"@ NS ns1.example.com.",
+		"host-1 A 192.0.2.11",
+		"host-2 A 192.0.2.12",
+		"host-3 A 192.0.2.13",
+		"www A 192.0.2.1",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// A missing include is an error.
+	if _, err := parseZoneContents("$INCLUDE missing.inc\n", "example.com", "example.com.zone", dir); err == nil {
+		t.Error("missing $INCLUDE was not reported")
+	}
+
+	// ParseZoneContents (used for snapshots) does not allow $INCLUDE.
+	if _, err := ParseZoneContents(zone, "example.com", filepath.Join(dir, "example.com.zone")); err == nil {
+		t.Error("ParseZoneContents allowed $INCLUDE")
+	}
+}
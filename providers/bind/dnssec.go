@@ -0,0 +1,463 @@
+package bind
+
+import (
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/miekg/dns"
+)
+
+// Inline signing: when a zone has AUTODNSSEC_ON and dnssec_key_dir is set,
+// the unsigned zone file is signed with the zone's keys and written to
+// "<zonefile>.signed". The keys are BIND key files
+// (K<zone>.+<alg>+<tag>.key and .private), as made by dnssec-keygen. Keys
+// with the SEP flag (KSKs) sign the DNSKEY RRset; the others (ZSKs) sign the
+// rest. If a zone has only one kind of key, it signs everything.
+//
+// The zone is compared (and the serial incremented) using the unsigned
+// file only. The signed file is regenerated when the unsigned file is
+// written, and when its signatures are about to expire.
+
+// signedSuffix is appended to the name of the zone file to make the name
+// of the signed zone file.
+const signedSuffix = ".signed"
+
+// defaultSignatureValidity is the default validity of the signatures, in
+// days.
+const defaultSignatureValidity = 30
+
+// dnssecKey is a key that signs a zone.
+type dnssecKey struct {
+	dnskey *dns.DNSKEY
+	signer crypto.Signer
+}
+
+// isKSK returns true if the key signs the DNSKEY RRset.
+func (k *dnssecKey) isKSK() bool {
+	return k.dnskey.Flags&dns.SEP != 0
+}
+
+// signOptions are the settings of the signer.
+type signOptions struct {
+	nsec3      bool
+	iterations uint16
+	salt       string // Hex. Empty for no salt.
+	validity   time.Duration
+}
+
+// initSignOptions checks the signing metadata and sets c.signOptions.
+func (c *bindProvider) initSignOptions() error {
+	if _, err := hex.DecodeString(c.NSEC3Salt); err != nil || len(c.NSEC3Salt) > 2*255 {
+		return fmt.Errorf("nsec3_salt (%v) must be at most 255 bytes in hex", c.NSEC3Salt)
+	}
+	if c.SignatureValidity < 0 {
+		return fmt.Errorf("signature_validity_days (%v) must be positive", c.SignatureValidity)
+	}
+	days := c.SignatureValidity
+	if days == 0 {
+		days = defaultSignatureValidity
+	}
+	c.signOptions = signOptions{
+		nsec3:      c.NSEC3,
+		iterations: c.NSEC3Iterations,
+		salt:       strings.ToUpper(c.NSEC3Salt),
+		validity:   time.Duration(days) * 24 * time.Hour,
+	}
+	return nil
+}
+
+// readDNSSECKeys reads the keys of zone from dir. Revoked keys are
+// skipped.
+func readDNSSECKeys(dir, zone string) ([]*dnssecKey, error) {
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	pubs, err := filepath.Glob(filepath.Join(dir, "K"+zone+".+*.key"))
+	if err != nil {
+		return nil, err
+	}
+	var keys []*dnssecKey
+	for _, pub := range pubs {
+		k, err := readDNSSECKey(pub, zone)
+		if err != nil {
+			return nil, err
+		}
+		if k.dnskey.Flags&dns.REVOKE == 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no DNSSEC keys for %s in %q", zone, dir)
+	}
+	return keys, nil
+}
+
+// readDNSSECKey reads a .key file and its .private file.
+func readDNSSECKey(pub, zone string) (*dnssecKey, error) {
+	f, err := os.Open(pub)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rr, err := dns.ReadRR(f, pub)
+	if err != nil {
+		return nil, fmt.Errorf("can't read DNSSEC key: %w", err)
+	}
+	dnskey, ok := rr.(*dns.DNSKEY)
+	if !ok || !strings.EqualFold(dnskey.Hdr.Name, zone+".") {
+		return nil, fmt.Errorf("%s: not a DNSKEY of %s", pub, zone)
+	}
+
+	priv := strings.TrimSuffix(pub, ".key") + ".private"
+	pf, err := os.Open(priv)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+	key, err := dnskey.ReadPrivateKey(pf, priv)
+	if err != nil {
+		return nil, fmt.Errorf("can't read DNSSEC key: %s: %w", priv, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key", priv)
+	}
+	return &dnssecKey{dnskey: dnskey, signer: signer}, nil
+}
+
+// canonicalCompare compares two domain names in the canonical order of
+// RFC 4034, section 6.1.
+func canonicalCompare(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// signZone signs the records of the zone origin. It returns the records of
+// the signed zone, sorted in canonical order.
+func signZone(origin string, records []dns.RR, keys []*dnssecKey, opts signOptions, now time.Time) ([]dns.RR, error) {
+	origin = dns.CanonicalName(origin)
+
+	// The RRsets, by name and type. Existing DNSSEC records are replaced.
+	rrsets := map[string]map[uint16][]dns.RR{}
+	add := func(rr dns.RR) {
+		name := dns.CanonicalName(rr.Header().Name)
+		rr.Header().Name = name
+		if rrsets[name] == nil {
+			rrsets[name] = map[uint16][]dns.RR{}
+		}
+		t := rr.Header().Rrtype
+		for _, old := range rrsets[name][t] {
+			if dns.IsDuplicate(old, rr) {
+				return
+			}
+		}
+		rrsets[name][t] = append(rrsets[name][t], rr)
+	}
+	for _, rr := range records {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeNSEC3PARAM:
+			continue
+		}
+		if !dns.IsSubDomain(origin, dns.CanonicalName(rr.Header().Name)) {
+			return nil, fmt.Errorf("%s is not in zone %s", rr.Header().Name, origin)
+		}
+		add(rr)
+	}
+
+	soas := rrsets[origin][dns.TypeSOA]
+	if len(soas) != 1 {
+		return nil, fmt.Errorf("zone %s must have one SOA record", origin)
+	}
+	soa := soas[0].(*dns.SOA)
+	// RFC 9077: the TTL of NSEC and NSEC3 records.
+	denialTTL := min(soa.Minttl, soa.Hdr.Ttl)
+
+	for _, k := range keys {
+		dnskey := dns.Copy(k.dnskey).(*dns.DNSKEY)
+		if dnskey.Hdr.Ttl == 0 {
+			dnskey.Hdr.Ttl = soa.Hdr.Ttl
+		}
+		add(dnskey)
+	}
+
+	// Find the delegations. The names below them are glue, and the types
+	// other than NS and DS at them are occluded.
+	var delegations []string
+	for name, types := range rrsets {
+		if _, ok := types[dns.TypeNS]; ok && name != origin {
+			delegations = append(delegations, name)
+		}
+	}
+	isDelegation := func(name string) bool { return slices.Contains(delegations, name) }
+	isGlue := func(name string) bool {
+		return slices.ContainsFunc(delegations, func(d string) bool { return name != d && dns.IsSubDomain(d, name) })
+	}
+	// authTypes returns the authoritative types at name, sorted.
+	authTypes := func(name string) []uint16 {
+		var types []uint16
+		for t := range rrsets[name] {
+			if !isDelegation(name) || t == dns.TypeNS || t == dns.TypeDS {
+				types = append(types, t)
+			}
+		}
+		slices.Sort(types)
+		return types
+	}
+	// isSigned returns true if the RRset of type t at name is signed.
+	isSigned := func(name string, t uint16) bool {
+		return !isDelegation(name) || t == dns.TypeDS
+	}
+
+	var names []string
+	for name := range rrsets {
+		if !isGlue(name) {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, canonicalCompare)
+
+	// Add the NSEC or NSEC3 chain.
+	if !opts.nsec3 {
+		for i, name := range names {
+			add(&dns.NSEC{
+				Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: denialTTL},
+				NextDomain: names[(i+1)%len(names)],
+				TypeBitMap: sortedTypes(append(authTypes(name), dns.TypeNSEC, dns.TypeRRSIG)),
+			})
+		}
+	} else {
+		if err := addNSEC3Chain(origin, names, authTypes, isSigned, denialTTL, opts, add); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sign the authoritative RRsets.
+	var ksks, zsks []*dnssecKey
+	for _, k := range keys {
+		if k.isKSK() {
+			ksks = append(ksks, k)
+		} else {
+			zsks = append(zsks, k)
+		}
+	}
+	if len(ksks) == 0 {
+		ksks = zsks
+	}
+	if len(zsks) == 0 {
+		zsks = ksks
+	}
+	inception := uint32(now.Add(-time.Hour).Unix()) // Allow for clock skew.
+	expiration := uint32(now.Add(opts.validity).Unix())
+	var sigs []dns.RR
+	for name, types := range rrsets {
+		if isGlue(name) {
+			continue
+		}
+		for _, t := range authTypes(name) {
+			if !isSigned(name, t) {
+				continue
+			}
+			signers := zsks
+			if t == dns.TypeDNSKEY {
+				signers = ksks
+			}
+			for _, k := range signers {
+				sig := &dns.RRSIG{
+					Hdr:        dns.RR_Header{Ttl: types[t][0].Header().Ttl},
+					Algorithm:  k.dnskey.Algorithm,
+					KeyTag:     k.dnskey.KeyTag(),
+					SignerName: origin,
+					Inception:  inception,
+					Expiration: expiration,
+				}
+				if err := sig.Sign(k.signer, types[t]); err != nil {
+					return nil, fmt.Errorf("signing %s %s: %w", name, dns.TypeToString[t], err)
+				}
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	for _, sig := range sigs {
+		add(sig)
+	}
+
+	return sortSignedZone(rrsets), nil
+}
+
+// addNSEC3Chain adds the NSEC3 chain of the authoritative names, and the
+// NSEC3PARAM record. The chain also covers the empty non-terminals.
+func addNSEC3Chain(origin string, names []string, authTypes func(string) []uint16, isSigned func(string, uint16) bool, ttl uint32, opts signOptions, add func(dns.RR)) error {
+	types := map[string][]uint16{}
+	for _, name := range names {
+		types[name] = authTypes(name)
+		if slices.ContainsFunc(types[name], func(t uint16) bool { return isSigned(name, t) }) {
+			types[name] = append(types[name], dns.TypeRRSIG)
+		}
+		// The empty non-terminals between name and origin.
+		for parent := name; parent != origin; {
+			i, _ := dns.NextLabel(parent, 0)
+			parent = parent[i:]
+			if _, ok := types[parent]; !ok && !slices.Contains(names, parent) {
+				types[parent] = nil
+			}
+		}
+	}
+	types[origin] = append(types[origin], dns.TypeNSEC3PARAM)
+
+	hashes := map[string]string{}
+	var sorted []string
+	for name := range types {
+		h := strings.ToLower(dns.HashName(name, dns.SHA1, opts.iterations, opts.salt))
+		if h == "" {
+			return fmt.Errorf("invalid NSEC3 salt %q", opts.salt)
+		}
+		hashes[h] = name
+		sorted = append(sorted, h)
+	}
+	slices.Sort(sorted)
+
+	for i, h := range sorted {
+		next := sorted[(i+1)%len(sorted)]
+		add(&dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: h + "." + origin, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: ttl},
+			Hash:       dns.SHA1,
+			Iterations: opts.iterations,
+			SaltLength: uint8(len(opts.salt) / 2),
+			Salt:       opts.salt,
+			HashLength: 20,
+			NextDomain: strings.ToUpper(next),
+			TypeBitMap: sortedTypes(types[hashes[h]]),
+		})
+	}
+	add(&dns.NSEC3PARAM{
+		Hdr:        dns.RR_Header{Name: origin, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET},
+		Hash:       dns.SHA1,
+		Iterations: opts.iterations,
+		SaltLength: uint8(len(opts.salt) / 2),
+		Salt:       opts.salt,
+	})
+	return nil
+}
+
+// sortedTypes sorts a type bitmap and removes the duplicates.
+func sortedTypes(types []uint16) []uint16 {
+	slices.Sort(types)
+	return slices.Compact(types)
+}
+
+// sortSignedZone lists the records in canonical order of their names, by
+// type, each RRset followed by its signatures.
+func sortSignedZone(rrsets map[string]map[uint16][]dns.RR) []dns.RR {
+	var names []string
+	for name := range rrsets {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, canonicalCompare)
+
+	var result []dns.RR
+	for _, name := range names {
+		sigs := map[uint16][]dns.RR{}
+		for _, rr := range rrsets[name][dns.TypeRRSIG] {
+			t := rr.(*dns.RRSIG).TypeCovered
+			sigs[t] = append(sigs[t], rr)
+		}
+		var types []uint16
+		for t := range rrsets[name] {
+			if t != dns.TypeRRSIG {
+				types = append(types, t)
+			}
+		}
+		// The SOA comes first.
+		slices.SortFunc(types, func(a, b uint16) int {
+			switch {
+			case a == dns.TypeSOA:
+				return -1
+			case b == dns.TypeSOA:
+				return 1
+			}
+			return int(a) - int(b)
+		})
+		for _, t := range types {
+			result = append(result, rrsets[name][t]...)
+			result = append(result, sigs[t]...)
+		}
+	}
+	return result
+}
+
+// writeSignedZoneFile signs the zone file and writes the signed zone.
+func writeSignedZoneFile(p printer.Printer, zonefile, origin string, keys []*dnssecKey, opts signOptions, now time.Time) error {
+	content, err := os.ReadFile(zonefile)
+	if err != nil {
+		return fmt.Errorf("can't read zonefile to sign: %w", err)
+	}
+	var records []dns.RR
+	zp := dns.NewZoneParser(strings.NewReader(string(content)), dns.Fqdn(origin), zonefile)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		records = append(records, rr)
+	}
+	if err := zp.Err(); err != nil {
+		return fmt.Errorf("error while parsing '%v': %w", zonefile, err)
+	}
+
+	signed, err := signZone(dns.Fqdn(origin), records, keys, opts, now)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "; signed with dnscontrol %s\n", now.Format(time.RFC3339))
+	for _, rr := range signed {
+		b.WriteString(rr.String())
+		b.WriteString("\n")
+	}
+	p.Printf("WRITING SIGNED ZONEFILE: %v\n", zonefile+signedSuffix)
+	if err := writeFileAtomic(zonefile+signedSuffix, []byte(b.String()), nil, 0); err != nil {
+		return fmt.Errorf("could not write signed zonefile: %w", err)
+	}
+	return nil
+}
+
+// needsResigning returns a reason to sign the zone again although the
+// unsigned zone did not change, or "". The signed file is missing, a key
+// is not used, or a signature expires in less than a quarter of the
+// validity.
+func needsResigning(signedFile string, keys []*dnssecKey, opts signOptions, now time.Time) string {
+	content, err := os.ReadFile(signedFile)
+	if err != nil {
+		return "signed zonefile is missing"
+	}
+	used := map[uint16]bool{}
+	zp := dns.NewZoneParser(strings.NewReader(string(content)), "", signedFile)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		used[sig.KeyTag] = true
+		// RFC 1982 arithmetic: the timestamps wrap around.
+		if left := time.Duration(int32(sig.Expiration-uint32(now.Unix()))) * time.Second; left < opts.validity/4 {
+			return "signatures expire soon"
+		}
+	}
+	if zp.Err() != nil {
+		return "signed zonefile is invalid"
+	}
+	for _, k := range keys {
+		if !used[k.dnskey.KeyTag()] {
+			return fmt.Sprintf("key %d is not used", k.dnskey.KeyTag())
+		}
+	}
+	return ""
+}
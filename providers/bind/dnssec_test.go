@@ -0,0 +1,213 @@
+package bind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/miekg/dns"
+)
+
+const testZone = `
+$ORIGIN example.com.
+$TTL 300
+@        IN SOA  ns1 hostmaster 2024010100 3600 600 604800 60
+@        IN NS   ns1
+ns1      IN A    192.0.2.1
+www      IN A    192.0.2.2
+a.b.c    IN TXT  "empty non-terminals above"
+sub      IN NS   ns.sub
+sub      IN DS   12345 13 2 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+ns.sub   IN A    192.0.2.3
+`
+
+// writeTestKey generates a key for example.com and writes its key files to
+// dir.
+func writeTestKey(t *testing.T, dir string, flags uint16) {
+	t.Helper()
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, fmt.Sprintf("Kexample.com.+013+%05d", k.KeyTag()))
+	if err := os.WriteFile(base+".key", []byte(k.String()+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".private", []byte(k.PrivateKeyString(priv)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func parseTestZone(t *testing.T) []dns.RR {
+	t.Helper()
+	var rrs []dns.RR
+	zp := dns.NewZoneParser(strings.NewReader(testZone), "example.com.", "test")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return rrs
+}
+
+func Test_signZone(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKey(t, dir, 257)
+	writeTestKey(t, dir, 256)
+	keys, err := readDNSSECKeys(dir, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("read %d keys, want 2", len(keys))
+	}
+	now := time.Now()
+
+	for _, nsec3 := range []bool{false, true} {
+		opts := signOptions{nsec3: nsec3, iterations: 0, salt: "", validity: 30 * 24 * time.Hour}
+		signed, err := signZone("example.com.", parseTestZone(t), keys, opts, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rrsets := map[string]map[uint16][]dns.RR{}
+		var sigs []*dns.RRSIG
+		var nsecs []*dns.NSEC
+		var nsec3s []*dns.NSEC3
+		for _, rr := range signed {
+			switch rr := rr.(type) {
+			case *dns.RRSIG:
+				sigs = append(sigs, rr)
+				continue
+			case *dns.NSEC:
+				nsecs = append(nsecs, rr)
+			case *dns.NSEC3:
+				nsec3s = append(nsec3s, rr)
+			}
+			h := rr.Header()
+			if rrsets[h.Name] == nil {
+				rrsets[h.Name] = map[uint16][]dns.RR{}
+			}
+			rrsets[h.Name][h.Rrtype] = append(rrsets[h.Name][h.Rrtype], rr)
+		}
+
+		// Every signature verifies with its key.
+		signedSets := map[string]bool{}
+		for _, sig := range sigs {
+			var key *dnssecKey
+			for _, k := range keys {
+				if k.dnskey.KeyTag() == sig.KeyTag {
+					key = k
+				}
+			}
+			if key == nil {
+				t.Fatalf("nsec3=%v: signature by unknown key %d", nsec3, sig.KeyTag)
+			}
+			if key.isKSK() != (sig.TypeCovered == dns.TypeDNSKEY) {
+				t.Errorf("nsec3=%v: %s %s signed by the wrong key", nsec3, sig.Hdr.Name, dns.TypeToString[sig.TypeCovered])
+			}
+			if err := sig.Verify(key.dnskey, rrsets[sig.Hdr.Name][sig.TypeCovered]); err != nil {
+				t.Errorf("nsec3=%v: %s %s: %v", nsec3, sig.Hdr.Name, dns.TypeToString[sig.TypeCovered], err)
+			}
+			if !sig.ValidityPeriod(now) {
+				t.Errorf("nsec3=%v: %s: signature not valid now", nsec3, sig.Hdr.Name)
+			}
+			signedSets[sig.Hdr.Name+"/"+dns.TypeToString[sig.TypeCovered]] = true
+		}
+		for _, want := range []string{"example.com./SOA", "example.com./DNSKEY", "www.example.com./A", "sub.example.com./DS"} {
+			if !signedSets[want] {
+				t.Errorf("nsec3=%v: %s is not signed", nsec3, want)
+			}
+		}
+		for _, notWant := range []string{"sub.example.com./NS", "ns.sub.example.com./A"} {
+			if signedSets[notWant] {
+				t.Errorf("nsec3=%v: %s is signed", nsec3, notWant)
+			}
+		}
+
+		if !nsec3 {
+			// One NSEC per authoritative name, in a loop.
+			if len(nsecs) != 5 {
+				t.Errorf("got %d NSEC records, want 5", len(nsecs))
+			}
+			for i, n := range nsecs {
+				if next := nsecs[(i+1)%len(nsecs)].Hdr.Name; n.NextDomain != next {
+					t.Errorf("NSEC %s next = %s, want %s", n.Hdr.Name, n.NextDomain, next)
+				}
+				if n.Hdr.Ttl != 60 {
+					t.Errorf("NSEC %s TTL = %d, want 60", n.Hdr.Name, n.Hdr.Ttl)
+				}
+			}
+			continue
+		}
+
+		// One NSEC3 per authoritative name and empty non-terminal.
+		if len(nsec3s) != 7 {
+			t.Errorf("got %d NSEC3 records, want 7", len(nsec3s))
+		}
+		for _, name := range []string{"example.com.", "c.example.com.", "b.c.example.com.", "sub.example.com."} {
+			found := false
+			for _, n := range nsec3s {
+				found = found || n.Match(name)
+			}
+			if !found {
+				t.Errorf("no NSEC3 matches %s", name)
+			}
+		}
+		for _, n := range nsec3s {
+			if n.Match("ns.sub.example.com.") {
+				t.Errorf("glue has an NSEC3 record")
+			}
+		}
+		if len(rrsets["example.com."][dns.TypeNSEC3PARAM]) != 1 {
+			t.Errorf("no NSEC3PARAM")
+		}
+	}
+}
+
+func Test_needsResigning(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKey(t, dir, 257)
+	keys, err := readDNSSECKeys(dir, "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zonefile := filepath.Join(dir, "example.com.zone")
+	if err := os.WriteFile(zonefile, []byte(testZone), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := signOptions{validity: 30 * 24 * time.Hour}
+	now := time.Now()
+
+	if reason := needsResigning(zonefile+signedSuffix, keys, opts, now); reason == "" {
+		t.Error("missing signed file does not need signing")
+	}
+	if err := writeSignedZoneFile(printer.DefaultPrinter, zonefile, "example.com", keys, opts, now); err != nil {
+		t.Fatal(err)
+	}
+	if reason := needsResigning(zonefile+signedSuffix, keys, opts, now); reason != "" {
+		t.Errorf("fresh signed file needs signing: %s", reason)
+	}
+	if reason := needsResigning(zonefile+signedSuffix, keys, opts, now.Add(25*24*time.Hour)); reason == "" {
+		t.Error("expiring signatures do not need signing")
+	}
+	writeTestKey(t, dir, 256)
+	keys, err = readDNSSECKeys(dir, "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason := needsResigning(zonefile+signedSuffix, keys, opts, now); reason == "" {
+		t.Error("new key does not need signing")
+	}
+}
@@ -0,0 +1,208 @@
+package bind
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/google/shlex"
+)
+
+// The named.conf include is a list of zone statements that dnscontrol
+// generates, one for each zone of the provider:
+//
+//	zone "example.com" {
+//		type primary;
+//		file "/var/named/zones/example.com.zone";
+//		also-notify { 192.0.2.1; };
+//		allow-transfer { 192.0.2.1; };
+//	};
+//
+// The zones are keyed by their unique name, so that zones with the same
+// name and different split horizon tags do not replace each other. The
+// statement of a tagged zone is named after the zone without its tag, and
+// a comment records the unique name:
+//
+//	zone "example.com" { // example.com!inside
+//
+// named rejects an include that lists a zone twice, so an include may only
+// list one of the zones that have the same name.
+//
+// The file is owned by dnscontrol; it is rewritten, not edited, so any
+// manual change is lost.
+
+const namedConfHeader = "// Generated by dnscontrol. Do not edit: changes will be overwritten.\n"
+
+// namedConfMu serializes the updates of the include, since zones are
+// processed concurrently.
+var namedConfMu sync.Mutex
+
+var (
+	namedConfZoneRe = regexp.MustCompile(`^zone "([^"]+)" \{(?: // (\S+))?`)
+	namedConfFileRe = regexp.MustCompile(`^\s*file "([^"]*)";`)
+)
+
+// zoneTypes are the zone types that may be used in the include. The zone
+// files are written by dnscontrol, so the zones must be primary zones.
+var zoneTypes = []string{"primary", "master"}
+
+// readNamedConf returns the zones listed in the include, by unique name,
+// and the file of each. A missing include lists no zones.
+func readNamedConf(fname string) (map[string]string, error) {
+	zones := map[string]string{}
+	content, err := os.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return zones, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read named.conf include: %w", err)
+	}
+
+	var zone string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := namedConfZoneRe.FindStringSubmatch(line); m != nil {
+			zone = m[1]
+			if m[2] != "" {
+				zone = m[2]
+			}
+			zones[zone] = ""
+		} else if m := namedConfFileRe.FindStringSubmatch(line); m != nil && zone != "" {
+			zones[zone] = m[1]
+		}
+	}
+	return zones, scanner.Err()
+}
+
+// renderNamedConf generates the include for zones, which are keyed by
+// unique name.
+func (c *bindProvider) renderNamedConf(zones map[string]string) []byte {
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b bytes.Buffer
+	b.WriteString(namedConfHeader)
+	for _, name := range names {
+		if domain, _, tagged := strings.Cut(name, "!"); tagged {
+			fmt.Fprintf(&b, "\nzone %q { // %s\n", domain, name)
+		} else {
+			fmt.Fprintf(&b, "\nzone %q {\n", name)
+		}
+		fmt.Fprintf(&b, "\ttype %s;\n", c.ZoneType)
+		fmt.Fprintf(&b, "\tfile %q;\n", zones[name])
+		if len(c.AlsoNotify) != 0 {
+			fmt.Fprintf(&b, "\talso-notify { %s; };\n", strings.Join(c.AlsoNotify, "; "))
+		}
+		if len(c.AllowTransfer) != 0 {
+			fmt.Fprintf(&b, "\tallow-transfer { %s; };\n", strings.Join(c.AllowTransfer, "; "))
+		}
+		b.WriteString("};\n")
+	}
+	return b.Bytes()
+}
+
+// checkNamedConf returns an error if two of zones, which are keyed by
+// unique name, have the same zone name: named rejects an include that lists
+// a zone twice.
+func checkNamedConf(zones map[string]string) error {
+	seen := map[string]string{}
+	for _, uniquename := range slices.Sorted(maps.Keys(zones)) {
+		name, _, _ := strings.Cut(uniquename, "!")
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("named.conf include can't list zone %q twice (as %s and %s): use one BIND provider, and one named_conf, per view", name, other, uniquename)
+		}
+		seen[name] = uniquename
+	}
+	return nil
+}
+
+// namedConfFile returns the name of a zone file as named sees it.
+func (c *bindProvider) namedConfFile(uniquename, domain, tag string) (string, error) {
+	name := makeFileName(c.filenameformat, uniquename, domain, tag)
+	if c.namedZoneDir != "" {
+		return filepath.Join(c.namedZoneDir, name), nil
+	}
+	return filepath.Abs(filepath.Join(c.directory, name))
+}
+
+// updateNamedConf applies update to the zones of the include and writes
+// the include if its content changed. It returns true if it did (or, if
+// write is false, would) change the include.
+func (c *bindProvider) updateNamedConf(update func(zones map[string]string), write bool) (bool, error) {
+	namedConfMu.Lock()
+	defer namedConfMu.Unlock()
+
+	zones, err := readNamedConf(c.namedConf)
+	if err != nil {
+		return false, err
+	}
+	old, err := os.ReadFile(c.namedConf)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("can't read named.conf include: %w", err)
+	}
+	update(zones)
+	if err := checkNamedConf(zones); err != nil {
+		return false, err
+	}
+	content := c.renderNamedConf(zones)
+	if bytes.Equal(old, content) {
+		return false, nil
+	}
+	if !write {
+		return true, nil
+	}
+
+	if err := writeFileAtomic(c.namedConf, content, nil, 0); err != nil {
+		return false, fmt.Errorf("could not write named.conf include: %w", err)
+	}
+	return true, nil
+}
+
+// addToNamedConf adds a zone, by unique name, to the include, or updates
+// its file.
+func (c *bindProvider) addToNamedConf(uniquename, file string, write bool) (bool, error) {
+	return c.updateNamedConf(func(zones map[string]string) { zones[uniquename] = file }, write)
+}
+
+// removeFromNamedConf removes a zone, by unique name, from the include.
+func (c *bindProvider) removeFromNamedConf(uniquename string) error {
+	_, err := c.updateNamedConf(func(zones map[string]string) { delete(zones, uniquename) }, true)
+	return err
+}
+
+// runPostWrite runs the post_write command for a zone and reports its
+// output to p. The command is killed if ctx is done.
+func (c *bindProvider) runPostWrite(ctx context.Context, p printer.Printer, uniquename, domain, tag string) error {
+	args, err := shlex.Split(c.postWrite)
+	if err != nil {
+		return fmt.Errorf("post_write: %w", err)
+	}
+	for i := range args {
+		if strings.Contains(args[i], "%") {
+			args[i] = makeFileName(args[i], uniquename, domain, tag)
+		}
+	}
+	p.Printf("RUNNING: %s\n", strings.Join(args, " "))
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if s := strings.TrimSpace(string(out)); s != "" {
+		p.Printf("%s: %s\n", domain, strings.ReplaceAll(s, "\n", "\n"+domain+": "))
+	}
+	if err != nil {
+		return fmt.Errorf("post_write %q: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+func Test_namedConf(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "named.conf.dnscontrol")
+	meta, _ := json.Marshal(map[string]any{
+		"also_notify":    []string{"192.0.2.1", "192.0.2.2"},
+		"allow_transfer": []string{"192.0.2.1"},
+	})
+	p, err := initBind(map[string]string{
+		"directory":      filepath.Join(dir, "zones"),
+		"named_conf":     conf,
+		"named_zone_dir": "/var/named",
+	}, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := p.(*bindProvider)
+
+	zones, err := api.ListZones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 0 {
+		t.Errorf("ListZones() without an include = %v, want none", zones)
+	}
+
+	// EnsureZoneExists leaves the include to the corrections.
+	if err := api.EnsureZoneExists("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(conf); !os.IsNotExist(err) {
+		t.Errorf("EnsureZoneExists wrote the include")
+	}
+
+	for _, z := range []string{"example.org", "example.com"} {
+		file, err := api.namedConfFile(z, z, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.addToNamedConf(z, file, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := namedConfHeader + `
+zone "example.com" {
+	type primary;
+	file "/var/named/example.com.zone";
+	also-notify { 192.0.2.1; 192.0.2.2; };
+	allow-transfer { 192.0.2.1; };
+};
+
+zone "example.org" {
+	type primary;
+	file "/var/named/example.org.zone";
+	also-notify { 192.0.2.1; 192.0.2.2; };
+	allow-transfer { 192.0.2.1; };
+};
+`
+	if string(got) != want {
+		t.Errorf("include =\n%s\nwant\n%s", got, want)
+	}
+
+	zones, err = api.ListZones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(zones, []string{"example.com", "example.org"}) {
+		t.Errorf("ListZones() = %v", zones)
+	}
+
+	// Adding a zone that is listed with the same file changes nothing.
+	if changed, err := api.addToNamedConf("example.com", "/var/named/example.com.zone", true); err != nil || changed {
+		t.Errorf("addToNamedConf(existing) = %v, %v; want false, nil", changed, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "zones"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "zones", "example.org.zone"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DeleteZone("example.org"); err != nil {
+		t.Fatal(err)
+	}
+	zones, err = api.ListZones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(zones, []string{"example.com"}) {
+		t.Errorf("ListZones() after DeleteZone = %v", zones)
+	}
+}
+
+func Test_namedConfTagged(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "named.conf.dnscontrol")
+	api := &bindProvider{namedConf: conf, ZoneType: "primary"}
+
+	for _, z := range []string{"example.com!inside", "example.net"} {
+		if _, err := api.addToNamedConf(z, "/var/named/"+z+".zone", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := namedConfHeader + `
+zone "example.com" { // example.com!inside
+	type primary;
+	file "/var/named/example.com!inside.zone";
+};
+
+zone "example.net" {
+	type primary;
+	file "/var/named/example.net.zone";
+};
+`
+	got, err := os.ReadFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("include =\n%s\nwant\n%s", got, want)
+	}
+
+	zones, err := readNamedConf(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 2 || zones["example.com!inside"] != "/var/named/example.com!inside.zone" {
+		t.Errorf("readNamedConf() = %v", zones)
+	}
+	names, err := api.ListZones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"example.com", "example.net"}) {
+		t.Errorf("ListZones() = %v", names)
+	}
+
+	// Another zone with the same name can't be listed in the same
+	// include, and the include is left alone.
+	for _, z := range []string{"example.com!outside", "example.com"} {
+		if _, err := api.addToNamedConf(z, "/var/named/"+z+".zone", true); err == nil || !strings.Contains(err.Error(), "twice") {
+			t.Errorf("addToNamedConf(%s) = %v, want an error", z, err)
+		}
+	}
+	if got, _ := os.ReadFile(conf); string(got) != want {
+		t.Errorf("include changed to\n%s", got)
+	}
+
+	if err := api.removeFromNamedConf("example.com!inside"); err != nil {
+		t.Fatal(err)
+	}
+	zones, err = readNamedConf(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := zones["example.com!inside"]; ok || len(zones) != 1 {
+		t.Errorf("readNamedConf() after remove = %v", zones)
+	}
+}
+
+func Test_initBindNamedConf(t *testing.T) {
+	for _, tt := range []struct {
+		config map[string]string
+		meta   string
+		err    string
+	}{
+		{nil, `{"zone_type": "secondary"}`, "zone_type"},
+		{map[string]string{"post_write": `"`}, ``, "post_write"},
+		{map[string]string{"post_write": `rndc reload %D`}, `{"zone_type": "master"}`, ""},
+	} {
+		_, err := initBind(tt.config, json.RawMessage(tt.meta))
+		if tt.err == "" && err != nil {
+			t.Errorf("initBind(%v, %s): %v", tt.config, tt.meta, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("initBind(%v, %s) = %v, want an error about %s", tt.config, tt.meta, err, tt.err)
+		}
+	}
+}
+
+func Test_runPostWrite(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	api := &bindProvider{postWrite: `sh -c "echo %D %T > ` + out + `"`}
+	if err := api.runPostWrite(context.Background(), printer.DefaultPrinter, "example.com!inside", "example.com", "inside"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "example.com inside\n" {
+		t.Errorf("post_write ran with %q", got)
+	}
+
+	api.postWrite = "false"
+	if err := api.runPostWrite(context.Background(), printer.DefaultPrinter, "example.com", "example.com", ""); err == nil {
+		t.Error("runPostWrite(false) succeeded")
+	}
+}
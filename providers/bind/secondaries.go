@@ -0,0 +1,66 @@
+package bind
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/bindserial"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/miekg/dns"
+)
+
+// secondaryTimeout limits how long a secondary is queried.
+const secondaryTimeout = 3 * time.Second
+
+// querySerial asks server for the SOA serial of zone.
+func querySerial(server, zone string) (uint32, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+	m.RecursionDesired = false
+	c := &dns.Client{Timeout: secondaryTimeout}
+	r, _, err := c.Exchange(m, server)
+	if err != nil {
+		return 0, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return 0, fmt.Errorf("%s", dns.RcodeToString[r.Rcode])
+	}
+	for _, rr := range r.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, fmt.Errorf("no SOA record in the answer")
+}
+
+// checkSecondaries warns about the secondaries whose serial for zone is
+// not before serial. They would not transfer the new zone.
+func (c *bindProvider) checkSecondaries(zone string, serial uint32) {
+	for _, server := range c.Secondaries {
+		theirs, err := querySerial(server, zone)
+		if err != nil {
+			printer.Warnf("%s: can't get the serial of secondary %s: %v\n", zone, server, err)
+			continue
+		}
+		if msg := secondaryWarning(serial, theirs); msg != "" {
+			printer.Warnf("%s: secondary %s %s\n", zone, server, msg)
+		}
+	}
+}
+
+// secondaryWarning explains why a secondary with serial theirs will not
+// transfer a zone with serial ours, or returns "".
+func secondaryWarning(ours, theirs uint32) string {
+	switch {
+	case bindserial.Less(theirs, ours):
+		return ""
+	case theirs == ours:
+		return fmt.Sprintf("already has serial %d: it will not transfer the new zone", ours)
+	default:
+		return fmt.Sprintf("has serial %d, ahead of %d: it will not transfer the new zone until the serial passes it (use --bindserial to set a serial past it)", theirs, ours)
+	}
+}
@@ -0,0 +1,54 @@
+package bind
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func Test_secondaryWarning(t *testing.T) {
+	for _, tt := range []struct {
+		ours, theirs uint32
+		warn         bool
+	}{
+		{2024031501, 2024031500, false},
+		{2024031500, 2024031500, true},
+		{2024031500, 2024031501, true},
+		{1, 0xFFFFFFFF, false}, // Ours wrapped around.
+		{0xFFFFFFFF, 1, true},
+	} {
+		if got := secondaryWarning(tt.ours, tt.theirs); (got != "") != tt.warn {
+			t.Errorf("secondaryWarning(%d, %d) = %q", tt.ours, tt.theirs, got)
+		}
+	}
+}
+
+func Test_querySerial(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("can't listen on UDP:", err)
+	}
+	mux := dns.NewServeMux()
+	mux.HandleFunc("example.com.", func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		soa, _ := dns.NewRR("example.com. 300 IN SOA ns1.example.com. hostmaster.example.com. 2024031507 3600 600 604800 60")
+		m.Answer = append(m.Answer, soa)
+		w.WriteMsg(m)
+	})
+	srv := &dns.Server{PacketConn: pc, Handler: mux}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	serial, err := querySerial(pc.LocalAddr().String(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial != 2024031507 {
+		t.Errorf("querySerial() = %d, want 2024031507", serial)
+	}
+	if _, err := querySerial(pc.LocalAddr().String(), "example.org"); err == nil {
+		t.Error("querySerial() for an unknown zone succeeded")
+	}
+}
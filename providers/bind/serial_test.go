@@ -46,7 +46,10 @@ func Test_generate_serial_1(t *testing.T) {
 		nowFunc = func() time.Time {
 			return tst.Today
 		}
-		found := generateSerial(tst.Given)
+		found, err := generateSerial("date", tst.Given)
+		if err != nil {
+			t.Fatalf("Test:%d/%v: %v", i, tst.Given, err)
+		}
 		if expected != found {
 			t.Fatalf("Test:%d/%v: Expected (%d) got (%d)\n", i, tst.Given, expected, found)
 		}
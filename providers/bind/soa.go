@@ -7,7 +7,9 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/pkg/soautil"
 )
 
-func makeSoa(origin string, defSoa *SoaDefaults, existing, desired *models.RecordConfig) (*models.RecordConfig, uint32) {
+// makeSoa returns the SOA record of a zone, and the next serial of the
+// zone according to strategy.
+func makeSoa(origin string, defSoa *SoaDefaults, existing, desired *models.RecordConfig, strategy string) (*models.RecordConfig, uint32, error) {
 	// Create a SOA record.  Take data from desired, existing, default,
 	// or hardcoded defaults.
 	soaRec := models.RecordConfig{}
@@ -43,7 +45,8 @@ func makeSoa(origin string, defSoa *SoaDefaults, existing, desired *models.Recor
 		panic(err) // Should never happen.
 	}
 
-	return &soaRec, generateSerial(soaRec.SoaSerial)
+	serial, err := generateSerial(strategy, soaRec.SoaSerial)
+	return &soaRec, serial, err
 }
 
 func firstNonNull(items ...string) string {
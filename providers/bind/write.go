@@ -0,0 +1,180 @@
+package bind
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files are written to a temporary file in the same directory, which is
+// renamed over the old file once it is complete. named (or a crash) never
+// sees a partially written file.
+
+// tmpPrefix starts the names of the temporary files.
+const tmpPrefix = ".dnscontrol-"
+
+// backupSuffix is appended to the name of a zone file to make the name of
+// its previous version. Older versions get ".1", ".2", etc.
+const backupSuffix = ".bak"
+
+// writeFileAtomic writes content to fname. If check is not nil, it must
+// accept content before fname is replaced. If backups is positive, that
+// many previous versions of fname are kept.
+func writeFileAtomic(fname string, content []byte, check func([]byte) error, backups int) error {
+	fname, err := preprocessFilename(fname)
+	if err != nil {
+		return err
+	}
+	if check != nil {
+		if err := check(content); err != nil {
+			return fmt.Errorf("not writing %s: %w", fname, err)
+		}
+	}
+
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(fname); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fname), tmpPrefix+filepath.Base(fname)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails once renamed.
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if backups > 0 {
+		if err := rotateBackups(fname, backups); err != nil {
+			return fmt.Errorf("could not back up %s: %w", fname, err)
+		}
+	}
+	return os.Rename(tmp.Name(), fname)
+}
+
+// backupName returns the name of the nth previous version of fname,
+// starting at 0.
+func backupName(fname string, n int) string {
+	if n == 0 {
+		return fname + backupSuffix
+	}
+	return fmt.Sprintf("%s%s.%d", fname, backupSuffix, n)
+}
+
+// isBackupOrTemp returns true if name is a backup or a temporary file.
+func isBackupOrTemp(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, tmpPrefix) || strings.HasSuffix(base, backupSuffix) {
+		return true
+	}
+	i := strings.LastIndex(base, backupSuffix+".")
+	if i < 0 {
+		return false
+	}
+	n := base[i+len(backupSuffix)+1:]
+	return n != "" && strings.Trim(n, "0123456789") == ""
+}
+
+// rotateBackups keeps fname as its first backup, and shifts the older
+// backups. fname itself is left in place.
+func rotateBackups(fname string, backups int) error {
+	if _, err := os.Stat(fname); os.IsNotExist(err) {
+		return nil
+	}
+	for n := backups - 1; n > 0; n-- {
+		err := os.Rename(backupName(fname, n-1), backupName(fname, n))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	bak := backupName(fname, 0)
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(fname, bak); err == nil {
+		return nil
+	}
+	// Hard links are not supported everywhere.
+	return copyFile(fname, bak)
+}
+
+// copyFile copies src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	// The mode of a new file is subject to the umask. Keep the source's.
+	if err := out.Chmod(fi.Mode().Perm()); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// checkZoneContents parses a zone file and checks that named can load it:
+// there is one SOA, at the apex, and no name has both a CNAME and other
+// records, and there are NS records at the apex.
+func checkZoneContents(content []byte, zoneName, zonefileName string) error {
+	records, err := ParseZoneContents(string(content), zoneName, zonefileName)
+	if err != nil {
+		return err
+	}
+
+	soas, apexNS := 0, 0
+	types := map[string]map[string]bool{}
+	for _, rec := range records {
+		name := strings.ToLower(rec.GetLabelFQDN())
+		if types[name] == nil {
+			types[name] = map[string]bool{}
+		}
+		types[name][rec.Type] = true
+		switch {
+		case rec.Type == "SOA" && rec.GetLabel() == "@":
+			soas++
+		case rec.Type == "SOA":
+			return fmt.Errorf("SOA record at %s, not at the apex", name)
+		case rec.Type == "NS" && rec.GetLabel() == "@":
+			apexNS++
+		}
+	}
+	if soas != 1 {
+		return fmt.Errorf("zone %s has %d SOA records, want 1", zoneName, soas)
+	}
+	for name, t := range types {
+		if t["CNAME"] && len(t) > 1 {
+			return fmt.Errorf("%s has a CNAME and other records", name)
+		}
+	}
+	if apexNS == 0 {
+		return fmt.Errorf("zone %s has no NS records at the apex; named will not load it", zoneName)
+	}
+	return nil
+}
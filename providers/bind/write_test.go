@@ -0,0 +1,147 @@
+package bind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_writeFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "example.com.zone")
+	read := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := writeFileAtomic(fname, []byte(v), nil, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{
+		fname:                "v3",
+		backupName(fname, 0): "v2",
+		backupName(fname, 1): "v1",
+	} {
+		if got := read(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(backupName(fname, 2)); !os.IsNotExist(err) {
+		t.Errorf("more than 2 backups kept")
+	}
+
+	// A rejected content leaves everything in place.
+	reject := func([]byte) error { return errors.New("rejected") }
+	if err := writeFileAtomic(fname, []byte("v4"), reject, 2); err == nil {
+		t.Error("rejected content was written")
+	}
+	if got := read(fname); got != "v3" {
+		t.Errorf("after a rejected write, file = %q", got)
+	}
+	if got := read(backupName(fname, 0)); got != "v2" {
+		t.Errorf("after a rejected write, backup = %q", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "example.com.zone" && !isBackupOrTemp(e.Name()) {
+			t.Errorf("unexpected file %s", e.Name())
+		}
+		if filepath.Base(e.Name())[0] == '.' {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func Test_isBackupOrTemp(t *testing.T) {
+	for name, want := range map[string]bool{
+		"example.com.zone":                 false,
+		"example.com.zone.bak":             true,
+		"example.com.zone.bak.12":          true,
+		"example.com.zone.bak.x":           false,
+		"bak.example.com.zone":             false,
+		".dnscontrol-example.com.zone.123": true,
+	} {
+		if got := isBackupOrTemp(name); got != want {
+			t.Errorf("isBackupOrTemp(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func Test_checkZoneContents(t *testing.T) {
+	const soa = "@ 300 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 60\n"
+	const ns = "@ 300 IN NS ns1.example.com.\n"
+	for _, tt := range []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"ok", soa + ns + "www 300 IN A 192.0.2.1\n", false},
+		{"no NS", soa + "www 300 IN A 192.0.2.1\n", true},
+		{"NS not at apex", soa + "sub 300 IN NS ns1.example.com.\n", true},
+		{"no SOA", ns, true},
+		{"two SOAs", soa + soa + ns, true},
+		{"SOA not at apex", soa + ns + "sub 300 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 60\n", true},
+		{"CNAME and other data", soa + ns + "www 300 IN CNAME example.org.\nwww 300 IN TXT \"x\"\n", true},
+		{"unparsable", soa + ns + "www 300 IN A not-an-ip\n", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkZoneContents([]byte(tt.content), "example.com", "test.zone")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkZoneContents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_initBindBackups(t *testing.T) {
+	for _, tt := range []struct {
+		config map[string]string
+		want   int
+	}{
+		{nil, 0}, // Backups are opt-in.
+		{map[string]string{"backups": "3"}, 3},
+	} {
+		p, err := initBind(tt.config, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.(*bindProvider).backups; got != tt.want {
+			t.Errorf("initBind(%v): backups = %d, want %d", tt.config, got, tt.want)
+		}
+	}
+	if _, err := initBind(map[string]string{"backups": "-1"}, nil); err == nil {
+		t.Error("initBind accepted backups -1")
+	}
+}
+
+func Test_copyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := os.WriteFile(src, []byte("zone"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(src, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o640 {
+		t.Errorf("mode of the copy: got %v, want %v", fi.Mode().Perm(), os.FileMode(0o640))
+	}
+}
@@ -131,7 +131,7 @@ type DocumentationNote struct {
 // DocumentationNotes is a full list of notes for a single provider
 type DocumentationNotes map[Capability]*DocumentationNote
 
-// ProviderMetadata is a common interface for DocumentationNotes and Capability to be used interchangeably
+// ProviderMetadata is a common interface for DocumentationNotes, Capability and CredsKeys to be used interchangeably
 type ProviderMetadata interface{}
 
 // Notes is a collection of all documentation notes, keyed by provider type
@@ -153,6 +153,13 @@ func unwrapProviderCapabilities(pName string, meta []ProviderMetadata) {
 				Notes[pName][k] = v
 				providerCapabilities[pName][k] = v.HasFeature
 			}
+		case CredsKeys:
+			if credsKeys[pName] == nil {
+				credsKeys[pName] = CredsKeys{}
+			}
+			for k, v := range x {
+				credsKeys[pName][k] = v
+			}
 		default:
 			log.Fatalf("Unrecognized ProviderMetadata type: %T", pm)
 		}
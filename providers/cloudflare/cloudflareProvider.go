@@ -65,6 +65,16 @@ var features = providers.DocumentationNotes{
 	providers.DocOfficiallySupported: providers.Can(),
 }
 
+// credsKeys are all optional: newCloudflare checks that either apitoken,
+// or apikey and apiuser, are set.
+var credsKeys = providers.CredsKeys{
+	"accountid": providers.CredsOptional,
+	"apikey":    providers.CredsOptional,
+	"apitoken":  providers.CredsOptional,
+	"apiuser":   providers.CredsOptional,
+	"proxy":     providers.CredsOptional,
+}
+
 func init() {
 	const providerName = "CLOUDFLAREAPI"
 	const providerMaintainer = "@tresni"
@@ -72,7 +82,7 @@ func init() {
 		Initializer:   newCloudflare,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, credsKeys)
 	providers.RegisterCustomRecordType("CF_REDIRECT", providerName, "")
 	providers.RegisterCustomRecordType("CF_TEMP_REDIRECT", providerName, "")
 	providers.RegisterCustomRecordType("CF_WORKER_ROUTE", providerName, "")
@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CredsKey says whether a creds.json key is required or optional.
+type CredsKey int
+
+const (
+	// CredsOptional is a key that may be omitted.
+	CredsOptional CredsKey = iota
+	// CredsRequired is a key that must be set to a non-empty value.
+	CredsRequired
+)
+
+// CredsKeys lists the creds.json keys that a provider accepts. Pass it to
+// RegisterDomainServiceProviderType or RegisterRegistrarType, along with
+// the capabilities, so that unknown and missing keys are reported before
+// the provider is initialized:
+//
+//	providers.RegisterDomainServiceProviderType(providerName, fns, features, providers.CredsKeys{
+//		"apikey": providers.CredsRequired,
+//		"region": providers.CredsOptional,
+//	})
+//
+// "TYPE" and the keys that start with "_" are always accepted.
+type CredsKeys map[string]CredsKey
+
+// credsKeys stores the accepted creds.json keys, keyed by provider type.
+// Providers that did not register any are not validated.
+var credsKeys = map[string]CredsKeys{}
+
+// ValidateCreds checks a creds.json entry against the keys registered by
+// its provider type. It returns the keys that the provider does not know
+// (probably typos), and an error if a required key is missing. A missing
+// or mismatched TYPE is left for CreateDNSProvider and CreateRegistrar to
+// report.
+func ValidateCreds(providerType string, config map[string]string) (unknown []string, err error) {
+	providerType, err = beCompatible(providerType, config)
+	if err != nil {
+		return nil, nil
+	}
+	keys, ok := credsKeys[providerType]
+	if !ok {
+		return nil, nil
+	}
+
+	for k := range config {
+		if _, ok := keys[k]; !ok && k != "TYPE" && !strings.HasPrefix(k, "_") {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+
+	var missing []string
+	for k, v := range keys {
+		if v == CredsRequired && config[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return unknown, fmt.Errorf("%s: missing required creds.json keys: %s", providerType, strings.Join(missing, ", "))
+	}
+	return unknown, nil
+}
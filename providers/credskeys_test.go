@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateCreds(t *testing.T) {
+	RegisterRegistrarType("CREDSKEYS_TEST", func(map[string]string) (Registrar, error) { return None{}, nil }, CredsKeys{
+		"apikey": CredsRequired,
+		"region": CredsOptional,
+	})
+
+	tests := []struct {
+		name        string
+		pType       string
+		config      map[string]string
+		wantUnknown []string
+		wantErr     bool
+	}{
+		{"ok", "-", map[string]string{"TYPE": "CREDSKEYS_TEST", "apikey": "k", "region": "eu", "_max_rps": "2"}, nil, false},
+		{"unknown", "-", map[string]string{"TYPE": "CREDSKEYS_TEST", "apikey": "k", "regoin": "eu", "Apikey": "k"}, []string{"Apikey", "regoin"}, false},
+		{"missing", "-", map[string]string{"TYPE": "CREDSKEYS_TEST", "region": "eu"}, nil, true},
+		{"empty", "CREDSKEYS_TEST", map[string]string{"apikey": ""}, nil, true},
+		{"unregistered", "-", map[string]string{"TYPE": "NONE", "anything": "x"}, nil, false},
+		{"no type", "-", map[string]string{"apikey": "k"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknown, err := ValidateCreds(tt.pType, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("unknown = %v, want %v", unknown, tt.wantUnknown)
+			}
+		})
+	}
+}
@@ -32,9 +32,12 @@ func newPacketframe(m map[string]string, metadata json.RawMessage) (providers.DN
 	if err != nil {
 		return nil, errors.New("invalid base URL for Packetframe")
 	}
-	client := http.Client{}
+	client, err := providers.WrapHTTPClient(&http.Client{}, m)
+	if err != nil {
+		return nil, err
+	}
 
-	api := &packetframeProvider{client: &client, baseURL: baseURL, token: m["token"]}
+	api := &packetframeProvider{client: client, baseURL: baseURL, token: m["token"]}
 
 	return api, nil
 }
@@ -51,6 +54,10 @@ var features = providers.DocumentationNotes{
 	providers.DocOfficiallySupported: providers.Cannot(),
 }
 
+var credsKeys = providers.CredsKeys{
+	"token": providers.CredsRequired,
+}
+
 func init() {
 	const providerName = "PACKETFRAME"
 	const providerMaintainer = "@hamptonmoore"
@@ -58,7 +65,7 @@ func init() {
 		Initializer:   newPacketframe,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, credsKeys)
 	providers.RegisterMaintainer(providerName, providerMaintainer)
 }
 
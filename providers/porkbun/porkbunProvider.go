@@ -100,15 +100,22 @@ var features = providers.DocumentationNotes{
 	providers.DocOfficiallySupported: providers.Cannot(),
 }
 
+var credsKeys = providers.CredsKeys{
+	"api_key":      providers.CredsRequired,
+	"secret_key":   providers.CredsRequired,
+	"max_attempts": providers.CredsOptional,
+	"max_duration": providers.CredsOptional,
+}
+
 func init() {
 	const providerName = "PORKBUN"
 	const providerMaintainer = "@imlonghao"
-	providers.RegisterRegistrarType(providerName, newReg)
+	providers.RegisterRegistrarType(providerName, newReg, credsKeys)
 	fns := providers.DspFuncs{
 		Initializer:   newDsp,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, credsKeys)
 	providers.RegisterMaintainer(providerName, providerMaintainer)
 	providers.RegisterCustomRecordType("PORKBUN_URLFWD", providerName, "")
 }
@@ -25,6 +25,13 @@ type ZoneCreator interface {
 	EnsureZoneExists(domain string) error
 }
 
+// ZoneDeleter should be implemented by providers that have the ability to
+// delete zones (used by --depopulate to remove zones that are not in
+// dnsconfig.js).
+type ZoneDeleter interface {
+	DeleteZone(domain string) error
+}
+
 // ZoneLister should be implemented by providers that have the
 // ability to list the zones they manage. This facilitates using the
 // "get-zones" command for "all" zones.
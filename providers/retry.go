@@ -0,0 +1,214 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"golang.org/x/time/rate"
+)
+
+// The creds.json keys that configure the rate limit and retries of a
+// provider that uses WrapHTTPClient.  For example:
+//
+//	"_max_rps": "5", "_retries": "3"
+const (
+	CredsMaxRPSKey  = "_max_rps"
+	CredsRetriesKey = "_retries"
+)
+
+const (
+	retryInitialBackoff = time.Second      // Delay before the first retry.
+	retryMaxBackoff     = 30 * time.Second // Maximum delay between retries.
+	retryMaxWait        = 5 * time.Minute  // Do not wait longer than this for Retry-After.
+)
+
+// RetryConfig configures a RetryTransport.
+type RetryConfig struct {
+	MaxRPS  float64 // Maximum requests per second (0 = no limit).
+	Retries int     // Number of retries after a 429 or 5xx response.
+}
+
+// RetryConfigFromCreds returns the RetryConfig set by the "_max_rps" and
+// "_retries" keys of a creds.json entry.  Missing keys mean no limit and
+// no retries.
+func RetryConfigFromCreds(config map[string]string) (RetryConfig, error) {
+	var rc RetryConfig
+	if v := config[CredsMaxRPSKey]; v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 {
+			return rc, fmt.Errorf("creds.json: invalid %s %q: must be a number greater than 0", CredsMaxRPSKey, v)
+		}
+		rc.MaxRPS = f
+	}
+	if v := config[CredsRetriesKey]; v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return rc, fmt.Errorf("creds.json: invalid %s %q: must be a whole number", CredsRetriesKey, v)
+		}
+		rc.Retries = i
+	}
+	return rc, nil
+}
+
+// WrapHTTPClient returns a copy of client (http.DefaultClient if nil)
+// whose requests are rate-limited and retried as configured by the
+// provider's creds.json entry.  Providers opt in by calling it once, when
+// the provider is created, and using the result for all API requests.
+func WrapHTTPClient(client *http.Client, config map[string]string) (*http.Client, error) {
+	rc, err := RetryConfigFromCreds(config)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := *client
+	c.Transport = NewRetryTransport(client.Transport, rc)
+	return &c, nil
+}
+
+// RetryTransport is an http.RoundTripper that limits the rate of requests
+// with a token bucket and retries requests that receive a 429 (Too Many
+// Requests) or 5xx response.
+//
+// A 429 means the request was not processed, so it is always retried. A
+// 5xx is only retried for idempotent methods (GET, HEAD, OPTIONS, PUT,
+// DELETE) since the request may have been processed.  Requests whose body
+// can not be sent again (see http.Request.GetBody) are not retried.
+//
+// The delay before a retry is the Retry-After header of the response if
+// there is one.  Otherwise it doubles with each retry, starting at 1s, up to
+// 30s.  Some jitter is added so that concurrent clients do not retry in
+// lockstep.
+type RetryTransport struct {
+	Base    http.RoundTripper // http.DefaultTransport if nil.
+	Retries int
+
+	limiter *rate.Limiter // nil if there is no limit.
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryTransport returns a RetryTransport that sends requests with base.
+func NewRetryTransport(base http.RoundTripper, rc RetryConfig) *RetryTransport {
+	t := &RetryTransport{Base: base, Retries: rc.Retries, sleep: sleepContext}
+	if rc.MaxRPS > 0 {
+		t.limiter = rate.NewLimiter(rate.Limit(rc.MaxRPS), 1)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if t.limiter != nil {
+			if err := t.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil || attempt >= t.Retries || !retryable(req, resp) {
+			return resp, err
+		}
+		delay, ok := retryDelay(resp, attempt)
+		if !ok {
+			return resp, nil
+		}
+
+		// Send the request again. An empty body (http.NoBody) can be
+		// sent again as is, even if GetBody is not set.
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		printer.Debugf("HTTP %d from %s, retrying in %s\n", resp.StatusCode, req.URL.Host, delay)
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable returns true if req may be sent again after receiving resp.
+func retryable(req *http.Request, resp *http.Response) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500 && resp.StatusCode <= 599:
+		switch req.Method {
+		case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before retrying after resp. It
+// returns false if the server asks to wait for longer than retryMaxWait.
+func retryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		if d > retryMaxWait {
+			return 0, false
+		}
+		// Up to 10% later, so that clients that got the same answer are
+		// spread out.
+		return d + rand.N(d/10+time.Millisecond), true
+	}
+
+	d := retryInitialBackoff << attempt
+	if d > retryMaxBackoff || d <= 0 {
+		d = retryMaxBackoff
+	}
+	// Between half and all of d.
+	return d/2 + rand.N(d/2+1), true
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryConfigFromCreds(t *testing.T) {
+	tests := []struct {
+		config  map[string]string
+		want    RetryConfig
+		wantErr bool
+	}{
+		{map[string]string{}, RetryConfig{}, false},
+		{map[string]string{"_max_rps": "5", "_retries": "3"}, RetryConfig{MaxRPS: 5, Retries: 3}, false},
+		{map[string]string{"_max_rps": "0.5"}, RetryConfig{MaxRPS: 0.5}, false},
+		{map[string]string{"_max_rps": "0"}, RetryConfig{}, true},
+		{map[string]string{"_max_rps": "fast"}, RetryConfig{}, true},
+		{map[string]string{"_retries": "-1"}, RetryConfig{}, true},
+		{map[string]string{"_retries": "1.5"}, RetryConfig{}, true},
+	}
+	for _, tt := range tests {
+		got, err := RetryConfigFromCreds(tt.config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: error = %v, wantErr %v", tt.config, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%v: got %+v, want %+v", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		v      string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"7", 7 * time.Second, true},
+		{"-1", 0, false},
+		{"Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Jan 2025 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.v, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.v, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		statuses  []int // Returned by the server, in order.
+		header    string
+		retries   int
+		wantCalls int
+		wantCode  int
+		wantSleep time.Duration // Minimum total delay.
+	}{
+		{"ok", "GET", []int{200}, "", 3, 1, 200, 0},
+		{"429 then ok", "POST", []int{429, 200}, "2", 3, 2, 200, 2 * time.Second},
+		{"503 GET", "GET", []int{503, 503, 200}, "", 3, 3, 200, time.Second},
+		{"503 POST", "POST", []int{503, 200}, "", 3, 1, 503, 0},
+		{"out of retries", "PUT", []int{429, 429, 429}, "0", 2, 3, 429, 0},
+		{"no retries", "GET", []int{429, 200}, "", 0, 1, 429, 0},
+		{"400", "GET", []int{400, 200}, "", 3, 1, 400, 0},
+		{"retry-after too long", "GET", []int{429, 200}, "3600", 3, 1, 429, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.statuses[calls])
+				calls++
+			}))
+			defer srv.Close()
+
+			var slept time.Duration
+			rt := NewRetryTransport(nil, RetryConfig{Retries: tt.retries})
+			rt.sleep = func(_ context.Context, d time.Duration) error {
+				slept += d
+				return nil
+			}
+			req, _ := http.NewRequest(tt.method, srv.URL, strings.NewReader("payload"))
+			resp, err := (&http.Client{Transport: rt}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status: got %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls: got %d, want %d", calls, tt.wantCalls)
+			}
+			if slept < tt.wantSleep {
+				t.Errorf("slept: got %s, want at least %s", slept, tt.wantSleep)
+			}
+			for i, b := range bodies {
+				if b != "payload" {
+					t.Errorf("body of request %d: got %q", i, b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransport_noBody(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	rt := NewRetryTransport(nil, RetryConfig{Retries: 2})
+	rt.sleep = func(context.Context, time.Duration) error { return nil }
+	req, _ := http.NewRequest("POST", srv.URL, nil)
+	req.Body = http.NoBody
+	req.GetBody = nil
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Errorf("got status %d after %d calls, want 200 after 2", resp.StatusCode, calls)
+	}
+}
+
+func TestRetryTransport_rateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := WrapHTTPClient(nil, map[string]string{"_max_rps": "50"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for range 5 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// The first request is immediate, the next 4 are 20ms apart.
+	if d := time.Since(start); d < 75*time.Millisecond {
+		t.Errorf("5 requests at 50/s took %s", d)
+	}
+}
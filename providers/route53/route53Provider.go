@@ -94,6 +94,15 @@ var features = providers.DocumentationNotes{
 	providers.DocOfficiallySupported: providers.Can(),
 }
 
+// credsKeys are all optional: without KeyId and SecretKey, the AWS SDK
+// finds the credentials in the environment.
+var credsKeys = providers.CredsKeys{
+	"KeyId":         providers.CredsOptional,
+	"SecretKey":     providers.CredsOptional,
+	"Token":         providers.CredsOptional,
+	"DelegationSet": providers.CredsOptional,
+}
+
 func init() {
 	const providerName = "ROUTE53"
 	const providerMaintainer = "@tresni"
@@ -101,8 +110,8 @@ func init() {
 		Initializer:   newRoute53Dsp,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
-	providers.RegisterRegistrarType(providerName, newRoute53Reg)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, credsKeys)
+	providers.RegisterRegistrarType(providerName, newRoute53Reg, credsKeys)
 	providers.RegisterCustomRecordType("R53_ALIAS", providerName, "")
 	providers.RegisterMaintainer(providerName, providerMaintainer)
 }